@@ -1,10 +1,25 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -15,449 +30,3501 @@ const (
 	RedisDB       = 0
 )
 
+// DefaultConnectTimeout 创建客户端时检查连接的默认超时时间，RedisConfig.ConnectTimeout为0时使用
+var DefaultConnectTimeout = 5 * time.Second
+
 // 检查redisClient是否实现了RedisClient的全部接口
 var _ RedisClient = (*redisClient)(nil)
 
+var (
+	// ErrClientClosed 客户端已关闭后继续调用方法时返回的错误
+	ErrClientClosed = errors.New("redis客户端已关闭")
+	// ErrWrongType 对键执行了与其类型不匹配的命令(如对字符串执行LRANGE)时返回的错误
+	ErrWrongType = errors.New("键的类型不匹配")
+	// ErrTimeout 阻塞命令在超时时间内没有获取到数据时返回的错误
+	ErrTimeout = errors.New("等待超时")
+	// ErrValueTooLarge 写入的值超过RedisConfig.MaxValueBytes时返回的错误
+	ErrValueTooLarge = errors.New("值超过大小上限")
+	// ErrInvalidKey 配置了ValidateKeys且键名包含空白或控制字符时返回的错误
+	ErrInvalidKey = errors.New("键名不合法")
+)
+
 type RedisClient interface {
 	// Set 设置键值对
 	Set(key, value string, expiration time.Duration) error
 	// Get 获取键的值
 	Get(key string) (string, error)
+	// GetInt 获取键的值并解析为整数
+	GetInt(key string) (int64, error)
+	// GetFloat 获取键的值并解析为浮点数
+	GetFloat(key string) (float64, error)
+	// GetBool 获取键的值并解析为布尔值
+	GetBool(key string) (bool, error)
 	// Delete 删除键
 	Delete(key string) error
+	// DeleteReport 批量删除键，返回实际存在并被删除的键
+	DeleteReport(keys ...string) (deleted []string, err error)
+	// DeleteByPattern 按批次删除匹配模式的所有键
+	DeleteByPattern(pattern string, batchSize int) (int64, error)
+	// SweepIdle 删除匹配模式且空闲时间超过阈值的键
+	SweepIdle(pattern string, idleThreshold time.Duration) (int64, error)
 	// Exists 检查键是否存在
 	Exists(key string) (bool, error)
+	// BatchExists 批量检查多个键是否存在
+	BatchExists(keys ...string) (map[string]bool, error)
+	// ExpireWithFlag 按条件(NX/XX/GT/LT)设置键的过期时间
+	ExpireWithFlag(key string, ttl time.Duration, flag string) (bool, error)
+	// PersistMany 批量移除多个键的过期时间
+	PersistMany(keys ...string) (map[string]bool, error)
 	// SetWithExpire 设置带过期时间的键值对
 	SetWithExpire(key, value string, expiration time.Duration) error
+	// SetAny 设置任意类型的值，非字符串类型使用JSON编码
+	SetAny(key string, value interface{}, ttl time.Duration) error
+	// SetBytes 原样写入二进制值
+	SetBytes(key string, value []byte, ttl time.Duration) error
+	// GetAny 获取键的值并解码到dest
+	GetAny(key string, dest interface{}) error
+	// SetObject 编码对象后写入，配置了Compress时使用gzip压缩
+	SetObject(key string, value interface{}, ttl time.Duration) error
+	// GetObject 读取对象并解码到dest，自动识别是否经过压缩
+	GetObject(key string, dest interface{}) error
+	// SetManyWithTTL 批量设置键值对，每个键可以有不同的过期时间
+	SetManyWithTTL(entries []SetEntry) error
+	// PipelineGet 批量获取多个键的值
+	PipelineGet(keys ...string) (map[string]string, error)
+	// PipelineGetObjects 批量获取多个键的值并解码为对象
+	PipelineGetObjects(keys []string, newDest func() interface{}) (map[string]interface{}, error)
+	// SetIfNewer 仅当版本号比已存储的版本更新时才写入
+	SetIfNewer(key string, version int64, value string, ttl time.Duration) (bool, error)
+	// SetNXGet 键不存在时设置值，否则返回当前值
+	SetNXGet(key, value string, ttl time.Duration) (acquired bool, current string, err error)
+	// RenameWithTTL 原子地将src重命名为dest并设置过期时间
+	RenameWithTTL(src, dest string, ttl time.Duration) error
 	// Increment 对数字值进行递增
 	Increment(key string) (int64, error)
+	// DecrementFloor 递减数字值，结果不会低于floor
+	DecrementFloor(key string, by int64, floor int64) (newValue int64, ok bool, err error)
+	// GetRangeInt 读取字符串指定字节范围的内容并解析为整数
+	GetRangeInt(key string, start, end int64) (int64, error)
+	// BitField 对字符串中的整数位段进行读写和自增
+	BitField(key string, args ...interface{}) ([]int64, error)
 	// ListRPush 从右侧推入列表元素
 	ListRPush(key string, values ...interface{}) error
+	// ListRPushCapped 从右侧推入列表元素，并只保留最新的max个元素
+	ListRPushCapped(key string, max int64, values ...interface{}) error
+	// ListLPushCapped 从左侧推入列表元素，并只保留最新的max个元素
+	ListLPushCapped(key string, max int64, values ...interface{}) error
 	// ListLLen 获取列表长度
 	ListLLen(key string) (int64, error)
+	// ListLLenMany 批量获取多个列表的长度
+	ListLLenMany(keys ...string) (map[string]int64, error)
 	// ListLPop 从左侧弹出列表元素
 	ListLPop(key string) (string, error)
+	// ListLMPop 从多个列表中第一个非空的列表弹出元素
+	ListLMPop(direction string, count int64, keys ...string) (key string, values []string, err error)
 	// ListLRange 获取列表指定范围的元素
 	ListLRange(key string, start, stop int64) ([]string, error)
+	// ListLRangeInt 获取列表指定范围的元素并解析为整数
+	ListLRangeInt(key string, start, stop int64) ([]int64, error)
+	// SortList 对列表(或集合/有序集合)元素进行排序
+	SortList(key string, sort *redis.Sort) ([]string, error)
 	// SetSAdd 添加元素到集合
 	SetSAdd(key string, members ...interface{}) error
+	// SetSAddCard 添加集合元素并返回添加后的元素数量
+	SetSAddCard(key string, members ...interface{}) (int64, error)
 	// SetSRem 移除集合中的元素
 	SetSRem(key string, members ...interface{}) error
+	// SetSRemReport 移除集合中的元素并返回实际移除的数量
+	SetSRemReport(key string, members ...interface{}) (removed int64, err error)
 	// SetSMembers 获取集合所有元素
 	SetSMembers(key string) ([]string, error)
+	// SetSMembersInt 获取集合所有元素并解析为整数(升序)
+	SetSMembersInt(key string) ([]int64, error)
+	// SetSMembersSet 获取集合所有元素，以map形式返回便于判断元素是否存在
+	SetSMembersSet(key string) (map[string]struct{}, error)
 	// SetSIsMember 检查元素是否在集合中
 	SetSIsMember(key string, member interface{}) (bool, error)
 	// SetSCard 获取集合元素数量
 	SetSCard(key string) (int64, error)
+	// SetSInterCard 获取多个集合交集的元素数量
+	SetSInterCard(limit int64, keys ...string) (int64, error)
+	// SetSCardMany 批量获取多个集合的元素数量
+	SetSCardMany(keys ...string) (map[string]int64, error)
 	// SetSRandMember 随机获取集合中的一个元素
 	SetSRandMember(key string) (string, error)
+	// SetSRandMemberN 随机获取集合中的多个元素
+	SetSRandMemberN(key string, count int64) ([]string, error)
+	// SetSNewSince 计算currKey相对prevKey新增的元素并存储到destKey
+	SetSNewSince(prevKey, currKey, destKey string) (int64, error)
 	// SetZAdd 添加/更新有序集合中的元素（带分数）
 	SetZAdd(key string, members ...redis.Z) error
+	// SetZAddGT 添加元素，已存在的元素仅当新分数更高时才更新
+	SetZAddGT(key string, members ...redis.Z) (int64, error)
+	// SetZAddLT 添加元素，已存在的元素仅当新分数更低时才更新
+	SetZAddLT(key string, members ...redis.Z) (int64, error)
+	// SetZUpdateScore 仅更新已存在元素的分数，不会新增元素
+	SetZUpdateScore(key, member string, score float64) (bool, error)
 	// SetZRem 移除有序集合中的元素
 	SetZRem(key string, members ...interface{}) error
+	// SetZRemRangeByRank 移除有序集合中指定排名范围的元素
+	SetZRemRangeByRank(key string, start, stop int64) (int64, error)
 	// SetZRange 获取有序集合指定范围的元素(按分数升序)
 	SetZRange(key string, start, stop int64) ([]string, error)
 	// SetZRevRange 获取有序集合指定范围的元素(按分数降序)
 	SetZRevRange(key string, start, stop int64) ([]string, error)
+	// SetZRevRangeWithScores 获取有序集合指定范围的元素及分数(按分数降序)
+	SetZRevRangeWithScores(key string, start, stop int64) ([]redis.Z, error)
+	// SetZTopN 获取有序集合中分数最高的n个元素及分数(按分数降序)
+	SetZTopN(key string, n int64) ([]redis.Z, error)
+	// SetZBottomN 获取有序集合中分数最低的n个元素及分数(按分数升序)
+	SetZBottomN(key string, n int64) ([]redis.Z, error)
 	// SetZCard 获取有序集合元素数量
 	SetZCard(key string) (int64, error)
+	// SetZCardMany 批量获取多个有序集合的元素数量
+	SetZCardMany(keys ...string) (map[string]int64, error)
+	// SetZCountRange 统计有序集合中分数在[min, max]范围内的元素数量
+	SetZCountRange(key string, min, max float64) (int64, error)
+	// SetZLexCount 统计有序集合中指定字典序范围内的元素数量
+	SetZLexCount(key, min, max string) (int64, error)
 	// SetZRangeByScore 获取有序集合指定分数范围内的元素(按分数升序)
 	SetZRangeByScore(key string, min, max string, start, stop int64) ([]string, error)
+	// SetZRangeByScoreLimit 获取有序集合指定分数范围内的元素(按分数升序)，offset/count对应LIMIT
+	SetZRangeByScoreLimit(key, min, max string, offset, count int64) ([]string, error)
 	// SetZRevRangeByScore 获取有序集合指定分数范围内的元素(按分数降序)
 	SetZRevRangeByScore(key string, min, max string, start, stop int64) ([]string, error)
+	// SetZRevRangeByScoreLimit 获取有序集合指定分数范围内的元素(按分数降序)，offset/count对应LIMIT
+	SetZRevRangeByScoreLimit(key, max, min string, offset, count int64) ([]string, error)
+	// SetZRangeByScoreWithScores 获取有序集合指定分数范围内的元素及分数(按分数升序)
+	SetZRangeByScoreWithScores(key, min, max string, offset, count int64) ([]redis.Z, error)
+	// SetZRevRangeByScoreWithScores 获取有序集合指定分数范围内的元素及分数(按分数降序)，参数顺序为先max后min
+	SetZRevRangeByScoreWithScores(key, max, min string, offset, count int64) ([]redis.Z, error)
+	// SetZRangeByScoreMap 获取有序集合指定分数范围内的元素及分数，以map形式返回
+	SetZRangeByScoreMap(key, min, max string) (map[string]float64, error)
+	// SetZRevRangeByLex 获取有序集合指定字典序范围内的元素(按字典序降序)
+	SetZRevRangeByLex(key, max, min string) ([]string, error)
 	// SetZScore 获取有序集合中元素的分数
 	SetZScore(key string, member string) error
+	// SetZScoreMap 批量获取有序集合中元素的分数
+	SetZScoreMap(key string, members ...string) (map[string]float64, error)
 	// SetZIncrBy 增加有序集合中元素的分数
-	SetZIncrBy(key string, member string, increment float64) error
+	SetZIncrBy(key string, member string, increment float64) (float64, error)
 	// SetZRank 获取有序集合中元素的排名（按分数升序）
 	SetZRank(key string, member string) error
 	// SetZRevRank 获取有序集合中元素的排名（按分数降序）
 	SetZRevRank(key string, member string) error
+	// SetZRankWithScore 同时获取有序集合中元素的排名（按分数升序）和分数
+	SetZRankWithScore(key, member string) (rank int64, score float64, err error)
+	// SetZPopMinCount 弹出有序集合中分数最低的count个元素(按分数升序返回)
+	SetZPopMinCount(key string, count int64) ([]redis.Z, error)
+	// SetZPopMaxCount 弹出有序集合中分数最高的count个元素(按分数降序返回)
+	SetZPopMaxCount(key string, count int64) ([]redis.Z, error)
+	// SetZMPop 从多个有序集合中第一个非空的集合弹出元素
+	SetZMPop(min bool, count int64, keys ...string) (key string, members []redis.Z, err error)
+	// SetBZMPop 阻塞地从多个有序集合中第一个非空的集合弹出元素
+	SetBZMPop(timeout time.Duration, min bool, count int64, keys ...string) (key string, members []redis.Z, err error)
+	// SetZUnion 获取多个有序集合的并集(不存储结果)
+	SetZUnion(store *redis.ZStore) ([]string, error)
+	// SetZUnionWithScores 获取多个有序集合的并集及分数(不存储结果)
+	SetZUnionWithScores(store *redis.ZStore) ([]redis.Z, error)
+	// SetZInter 获取多个有序集合的交集(不存储结果)
+	SetZInter(store *redis.ZStore) ([]string, error)
+	// SetZInterWithScores 获取多个有序集合的交集及分数(不存储结果)
+	SetZInterWithScores(store *redis.ZStore) ([]redis.Z, error)
+	// SetZDiffWithScores 获取第一个有序集合与其他集合的差集及分数
+	SetZDiffWithScores(keys ...string) ([]redis.Z, error)
+	// SetZScanPairs 增量遍历有序集合，返回元素及分数
+	SetZScanPairs(key string, cursor uint64, match string, count int64) ([]redis.Z, uint64, error)
 	// SetHashSet 设置哈希字段
 	HashSet(hashKey string, values ...interface{}) error
+	// HashSetWithTTL 设置多个哈希字段并为整个哈希设置过期时间
+	HashSetWithTTL(hashKey string, fields map[string]string, ttl time.Duration) error
 	// SetHashGetAll 获取哈希字段的所有值
 	HashGetAll(hashKey string) (map[string]string, error)
+	// HashGetAllBatch 批量获取多个哈希的所有字段
+	HashGetAllBatch(hashKeys ...string) (map[string]map[string]string, error)
 	// SetHashGet 获取哈希字段的值
 	HashGet(hashKey string, field string) (string, error)
+	// HashGetInt 获取哈希字段的值并解析为整数
+	HashGetInt(hashKey, field string) (int64, error)
+	// HashGetFloat 获取哈希字段的值并解析为浮点数
+	HashGetFloat(hashKey, field string) (float64, error)
+	// HashGetBool 获取哈希字段的值并解析为布尔值
+	HashGetBool(hashKey, field string) (bool, error)
+	// HashIncrByCapped 增加哈希字段的值，结果不会超过max
+	HashIncrByCapped(hashKey, field string, delta, max int64) (int64, bool, error)
+	// HashCompareAndSet 版本号匹配时更新哈希字段并递增版本号
+	HashCompareAndSet(hashKey, versionField string, expectedVersion int64, updates map[string]string) (bool, error)
+	// HashFieldExpire 设置哈希字段的过期时间
+	HashFieldExpire(hashKey string, ttl time.Duration, fields ...string) ([]int64, error)
+	// HashDeleteField 从多个哈希中删除同一个字段
+	HashDeleteField(field string, hashKeys ...string) (int64, error)
+	// HashPage 分页遍历哈希字段
+	HashPage(hashKey string, cursor uint64, pageSize int64) (fields map[string]string, next uint64, err error)
+	// HashScanMatch 获取字段名匹配模式的所有哈希字段
+	HashScanMatch(hashKey, pattern string) (map[string]string, error)
+	// HashDeleteAll 删除整个哈希
+	HashDeleteAll(hashKey string) (bool, error)
+	// AcquireLockWithRenewal 获取分布式锁，并在持有期间自动续期
+	AcquireLockWithRenewal(key string, ttl, renewEvery time.Duration) (release func(), ok bool, err error)
+	// NewScript 创建可复用的Lua脚本
+	NewScript(src string) *Script
+	// ScanEach 遍历匹配模式的键，对每个键调用fn
+	ScanEach(match string, count int64, fn func(key string) error) error
+	// Export 导出匹配模式的键及其值
+	Export(pattern string) (map[string]interface{}, error)
+	// ExportSnapshot 导出匹配模式的键及其值，结果可由Import还原
+	ExportSnapshot(pattern string) (map[string]interface{}, error)
+	// Import 将ExportSnapshot导出的数据写回Redis
+	Import(data map[string]interface{}) error
+	// ClientList 获取所有客户端连接信息
+	ClientList() ([]string, error)
+	// ClientKill 关闭指定地址的客户端连接
+	ClientKill(addr string) error
+	// Warmup 预先建立MinIdleConns个连接
+	Warmup() error
+	// Time 获取Redis服务器时间
+	Time() (time.Time, error)
+	// DebugObject 获取键的内部调试信息(仅用于测试)
+	DebugObject(key string) (string, error)
+	// DebugSleep 让Redis服务器暂停指定时间(仅用于测试)
+	DebugSleep(d time.Duration) error
+	// SubscribeHandler 订阅频道并在后台回调处理消息，返回停止订阅的函数
+	SubscribeHandler(channels []string, handler func(channel, payload string)) (stop func(), err error)
+	// SubscribeContext 订阅频道，ctx取消时自动取消订阅并关闭消息通道
+	SubscribeContext(ctx context.Context, channels ...string) (<-chan *redis.Message, error)
+	// OnInvalidation 订阅缓存失效频道，收到的每个键名都会回调handler
+	OnInvalidation(channel string, handler func(key string)) (stop func(), err error)
+	// BulkLoad 创建按批次自动提交的批量写入器
+	BulkLoad(size int) *BulkLoader
+	// WithTimeout 返回为每次操作单独设置超时时间的客户端
+	WithTimeout(d time.Duration) RedisClient
+	// OnClose 注册关闭客户端时执行的清理函数
+	OnClose(fn func())
+	// Reset 按创建时的配置重新创建底层连接池
+	Reset() error
 	// Close 关闭Redis连接
 	Close()
 }
 
 // redisClient 封装Redis客户端
 type redisClient struct {
-	client *redis.Client
-	ctx    context.Context
+	ref     *clientRef // 底层go-redis客户端，拷贝之间共享，Reset时整体替换
+	ctx     context.Context
+	config  RedisConfig
+	closed  *atomic.Bool    // 客户端是否已关闭，WithTimeout/WithContext返回的拷贝共享该标记
+	onClose *closeCallbacks // Close时执行的清理函数，拷贝之间共享
+}
+
+// clientRef 保存当前使用的go-redis客户端，以及Reset时用于重新创建客户端的函数
+type clientRef struct {
+	mu      sync.RWMutex
+	current redis.UniversalClient
+	factory func() redis.UniversalClient // 为nil表示客户端由外部注入，不支持Reset
+}
+
+// client 返回当前使用的go-redis客户端
+func (rc *redisClient) client() redis.UniversalClient {
+	rc.ref.mu.RLock()
+	defer rc.ref.mu.RUnlock()
+	return rc.ref.current
+}
+
+// closeCallbacks 通过OnClose注册的清理函数
+type closeCallbacks struct {
+	mu      sync.Mutex
+	fns     []func()
+	closing bool // Close已开始执行，之后注册的清理函数立即执行
 }
 
 type RedisConfig struct {
 	Addr         string // Redis地址，格式为"host:port"
+	Username     string // Redis ACL用户名，为空时仅使用密码认证
 	Password     string // Redis密码
 	DB           int    // Redis数据库索引
 	PoolSize     int    // 连接池大小
 	MinIdleConns int    // 最小空闲连接数
 	MaxRetries   int    // 最大重试次数
+	Identity     string // 连接名称(CLIENT SETNAME)，便于在CLIENT LIST中区分服务
+
+	ConnMaxIdleTime time.Duration // 连接的最大空闲时间，超过后关闭，应小于防火墙/代理的空闲断开时间；为0时使用go-redis默认值(30分钟)，-1表示不限制
+	ConnMaxLifetime time.Duration // 连接的最大复用时间，超过后关闭并重新建立；为0表示不限制
+
+	BeforeOp func(op, key string)            // 每条命令执行前的回调，可用于审计
+	AfterOp  func(op, key string, err error) // 每条命令执行后的回调，键不存在(redis.Nil)不视为错误
+
+	RetryPolicy *RetryPolicy // 只读/幂等命令的重试策略，为nil时不重试
+
+	SlidingTTL time.Duration // 大于0时每次Get都会将键的过期时间重置为该值(滑动过期)
+
+	Compress bool // 为true时SetObject使用gzip压缩编码后的值
+
+	MaxValueBytes int // 大于0时所有写入字符串值的方法(Set/SetAny/SetBytes/SetObject/SetWithExpire/SetManyWithTTL/SetNXGet/SetIfNewer/BulkLoader.Set/Import)拒绝写入超过该字节数的值，返回ErrValueTooLarge
+
+	ValidateKeys bool // 为true时拒绝执行键名包含空白或控制字符的命令，返回ErrInvalidKey
+
+	// ClientCache 为true时使用RESP3(Protocol=3)和服务端辅助失效(CLIENT TRACKING)开启go-redis的客户端缓存，
+	// 重复读取未变化的键(GET/HGET/HGETALL等)时直接返回本地缓存；任意客户端(包括本客户端)修改键后Redis推送失效消息，本地缓存随之删除
+	// 失效消息是异步处理的，修改后的几毫秒内仍可能读到旧值；缓存会额外占用进程内存，条目数由ClientCacheMaxEntries限制
+	// 仅支持0号数据库，需Redis 6.0+；服务端拒绝CLIENT TRACKING时go-redis会关闭客户端缓存，命令照常发往服务端
+	// 开启后go-redis会拒绝SELECT、CLIENT TRACKING等改变连接状态的命令
+	ClientCache           bool
+	ClientCacheMaxEntries int // 客户端缓存最多保存的条目数，为0时使用go-redis的默认值
+
+	LazyConnect    bool          // 为true时创建客户端时不检查连接，由第一条命令建立连接
+	ConnectTimeout time.Duration // 创建客户端时检查连接的超时时间，为0时使用DefaultConnectTimeout
+
+	Tracer Tracer // 链路追踪，为nil时不追踪
+
+	DebugCommands bool // 为true时记录实际发送的每条命令及其回复(截断)，仅用于排查问题
+
+	OnConnectionChange  func(connected bool) // 连接状态在断开与恢复之间变化时的回调，设置后会在后台定期PING
+	HealthCheckInterval time.Duration        // 后台PING的间隔，为0时使用5s
+}
+
+// Tracer 链路追踪接口，可对接OpenTelemetry等实现
+// StartSpan在命令执行前调用，返回的函数在命令执行完成后以命令的错误调用(键不存在不视为错误)
+type Tracer interface {
+	StartSpan(ctx context.Context, name string) (context.Context, func(error))
+}
+
+// RetryPolicy 带随机抖动的指数退避重试策略，仅作用于只读/幂等命令
+// 与go-redis的MaxRetries不同，非幂等命令(如INCR)不会被重试
+type RetryPolicy struct {
+	Attempts  int           // 最大尝试次数(包含第一次执行)
+	BaseDelay time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxDelay  time.Duration // 单次等待时间上限(包含抖动)
+	Jitter    time.Duration // 每次等待时额外增加的随机时间上限
+}
+
+// backoff 返回第attempt次重试(从0开始)前的等待时间
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt)
+	if delay < 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(p.Jitter)))
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
 }
 
 // NewRedisClient 创建Redis客户端实例
 func NewRedisClient(config *RedisConfig, ctx context.Context) (*redisClient, error) {
-	client := redis.NewClient(&redis.Options{
+	client := newClientFromConfig(config)
+
+	if config.LazyConnect {
+		log.Println("已创建Redis客户端(延迟连接)")
+	} else {
+		if err := pingWithTimeout(client, config.ConnectTimeout); err != nil {
+			return nil, fmt.Errorf("无法连接到Redis: %w", err)
+		}
+		log.Println("成功连接到Redis")
+	}
+
+	rc := NewRedisClientFromClient(client, ctx)
+	rc.config = *config
+	rc.ref.factory = func() redis.UniversalClient {
+		return newClientFromConfig(&rc.config)
+	}
+	rc.addConfigHooks(client)
+	if config.OnConnectionChange != nil {
+		interval := config.HealthCheckInterval
+		if interval <= 0 {
+			interval = 5 * time.Second
+		}
+		rc.monitorConnection(interval, config.OnConnectionChange)
+	}
+	return rc, nil
+}
+
+// newClientFromConfig 根据配置创建单节点go-redis客户端
+func newClientFromConfig(config *RedisConfig) *redis.Client {
+	opts := &redis.Options{
 		Addr:         config.Addr,
+		Username:     config.Username,
 		Password:     config.Password,
 		DB:           config.DB,
 		PoolSize:     config.PoolSize,
 		MinIdleConns: config.MinIdleConns,
 		MaxRetries:   config.MaxRetries,
+		ClientName:   config.Identity,
 		DialTimeout:  5 * time.Second,
 		ReadTimeout:  3 * time.Second,
 		WriteTimeout: 3 * time.Second,
-	})
-
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+		// ConnectTimeout和WithTimeout通过context的截止时间生效，需要让读写超时遵循context
+		ContextTimeoutEnabled: true,
 
-	// 需5s内连接成功，否则报错
-	_, err := client.Ping(timeoutCtx).Result()
-	if err != nil {
-		return nil, fmt.Errorf("无法连接到Redis: %v", err)
+		ConnMaxIdleTime: config.ConnMaxIdleTime,
+		ConnMaxLifetime: config.ConnMaxLifetime,
 	}
-
-	log.Println("成功连接到Redis")
-	return &redisClient{
-		client: client,
-		ctx:    ctx,
-	}, nil
+	if config.ClientCache {
+		opts.Protocol = 3
+		opts.ClientSideCacheConfig = &redis.ClientSideCacheConfig{MaxEntries: config.ClientCacheMaxEntries}
+	}
+	return redis.NewClient(opts)
 }
 
-// Set 设置键值对
-func (rc *redisClient) Set(key, value string, expiration time.Duration) error {
-	err := rc.client.Set(rc.ctx, key, value, expiration).Err()
-	if err != nil {
-		return fmt.Errorf("设置键值对失败: %v", err)
+// pingWithTimeout 在timeout内执行PING检查连接，timeout为0时使用DefaultConnectTimeout
+func pingWithTimeout(client redis.UniversalClient, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultConnectTimeout
 	}
-	log.Printf("设置成功: %s -> %s", key, value)
-	return nil
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return client.Ping(timeoutCtx).Err()
 }
 
-// Get 获取键的值
-func (rc *redisClient) Get(key string) (string, error) {
-	value, err := rc.client.Get(rc.ctx, key).Result()
-	if err == redis.Nil {
-		return "", fmt.Errorf("键不存在: %s", key)
-	} else if err != nil {
-		return "", fmt.Errorf("获取键值失败: %v", err)
+// addConfigHooks 按rc.config为客户端添加审计、追踪、重试等可选的hook
+func (rc *redisClient) addConfigHooks(client redis.UniversalClient) {
+	config := &rc.config
+	if config.ValidateKeys {
+		client.AddHook(keyValidationHook{})
+	}
+	if config.BeforeOp != nil || config.AfterOp != nil {
+		client.AddHook(opHook{before: config.BeforeOp, after: config.AfterOp})
+	}
+	if config.Tracer != nil {
+		client.AddHook(tracingHook{tracer: config.Tracer})
+	}
+	if config.RetryPolicy != nil && config.RetryPolicy.Attempts > 1 {
+		client.AddHook(retryHook{policy: config.RetryPolicy})
+	}
+	if config.DebugCommands {
+		client.AddHook(debugHook{})
 	}
-	log.Printf("获取成功: %s -> %s", key, value)
-	return value, nil
 }
 
-// Delete 删除键
-func (rc *redisClient) Delete(key string) error {
-	err := rc.client.Del(rc.ctx, key).Err()
-	if err != nil {
-		return fmt.Errorf("删除键失败: %v", err)
-	}
-	log.Printf("删除成功: %s", key)
-	return nil
+// monitorConnection 在后台每隔interval执行一次PING，连接状态在断开与恢复之间变化时调用onChange
+// 初始状态视为已连接，监控goroutine在Close时停止
+func (rc *redisClient) monitorConnection(interval time.Duration, onChange func(connected bool)) {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		connected := true
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(rc.ctx, interval)
+				err := rc.client().Ping(ctx).Err()
+				cancel()
+				if rc.closed.Load() {
+					return
+				}
+				if ok := err == nil; ok != connected {
+					connected = ok
+					if ok {
+						log.Println("Redis连接已恢复")
+					} else {
+						log.Printf("Redis连接已断开: %v", err)
+					}
+					onChange(ok)
+				}
+			}
+		}
+	}()
+	rc.OnClose(func() {
+		close(stop)
+		<-done
+	})
 }
 
-// Exists 检查键是否存在
-func (rc *redisClient) Exists(key string) (bool, error) {
-	result, err := rc.client.Exists(rc.ctx, key).Result()
-	if err != nil {
-		return false, fmt.Errorf("检查键存在失败: %v", err)
+// NewUniversalClient 根据配置自动选择单节点、集群或哨兵模式创建Redis客户端实例
+// 指定MasterName时使用哨兵模式，Addrs多于一个时使用集群模式，否则使用单节点模式
+func NewUniversalClient(opts *redis.UniversalOptions, ctx context.Context) (*redisClient, error) {
+	client := redis.NewUniversalClient(opts)
+
+	// 需在DefaultConnectTimeout内连接成功，否则报错
+	if err := pingWithTimeout(client, DefaultConnectTimeout); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("无法连接到Redis: %w", err)
 	}
-	exists := result > 0
-	log.Printf("键 %s 存在: %v, result: %v", key, exists, result)
-	return exists, nil
-}
+	log.Println("成功连接到Redis")
 
-// SetWithExpire 设置带过期时间的键值对
-func (rc *redisClient) SetWithExpire(key, value string, expiration time.Duration) error {
-	err := rc.client.SetEx(rc.ctx, key, value, expiration).Err()
-	if err != nil {
-		return fmt.Errorf("设置带过期时间的键值对失败: %v", err)
+	rc := NewRedisClientFromClient(client, ctx)
+	rc.ref.factory = func() redis.UniversalClient {
+		return redis.NewUniversalClient(opts)
 	}
-	log.Printf("设置带过期时间成功: %s -> %s (过期时间: %v)", key, value, expiration)
-	return nil
-}
+	rc.config = RedisConfig{
+		Username:     opts.Username,
+		Password:     opts.Password,
+		DB:           opts.DB,
+		PoolSize:     opts.PoolSize,
+		MinIdleConns: opts.MinIdleConns,
+		MaxRetries:   opts.MaxRetries,
+		Identity:     opts.ClientName,
 
-// Increment 对数字值进行递增
-func (rc *redisClient) Increment(key string) (int64, error) {
-	result, err := rc.client.Incr(rc.ctx, key).Result()
-	if err != nil {
-		return 0, fmt.Errorf("递增操作失败: %v", err)
+		ConnMaxIdleTime: opts.ConnMaxIdleTime,
+		ConnMaxLifetime: opts.ConnMaxLifetime,
 	}
-	log.Printf("递增成功: %s -> %d", key, result)
-	return result, nil
+	if len(opts.Addrs) > 0 {
+		rc.config.Addr = opts.Addrs[0]
+	}
+	return rc, nil
 }
 
-// ListRPush 从右侧推入列表元素
-func (rc *redisClient) ListRPush(key string, values ...interface{}) error {
-	err := rc.client.RPush(rc.ctx, key, values...).Err()
-	if err != nil {
-		return fmt.Errorf("推入列表元素失败: %v", err)
+// NewRedisClientFromClient 使用已有的redis客户端创建实例，不会主动连接Redis
+// 便于在单元测试中注入miniredis或mock客户端
+func NewRedisClientFromClient(client redis.UniversalClient, ctx context.Context) *redisClient {
+	rc := &redisClient{
+		ref:     &clientRef{current: client},
+		ctx:     ctx,
+		closed:  new(atomic.Bool),
+		onClose: &closeCallbacks{},
 	}
-	log.Printf("列表元素推入成功: %s -> %v", key, values)
+	rc.addBaseHooks(client)
+	return rc
+}
 
-	return nil
+// addBaseHooks 添加所有客户端都需要的hook: 关闭检查、WRONGTYPE错误包装和单次操作超时
+func (rc *redisClient) addBaseHooks(client redis.UniversalClient) {
+	client.AddHook(closedHook{closed: rc.closed})
+	client.AddHook(wrongTypeHook{})
+	client.AddHook(timeoutHook{})
 }
 
-// ListLLen 获取列表长度
-func (rc *redisClient) ListLLen(key string) (int64, error) {
-	length, err := rc.client.LLen(rc.ctx, key).Result()
-	if err != nil {
-		return 0, fmt.Errorf("获取列表长度失败: %v", err)
+// closedHook 客户端关闭后拒绝执行任何命令，返回ErrClientClosed
+type closedHook struct {
+	closed *atomic.Bool
+}
+
+func (h closedHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h closedHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if h.closed.Load() {
+			return ErrClientClosed
+		}
+		return next(ctx, cmd)
 	}
-	log.Printf("列表长度: %d", length)
-	return length, nil
 }
 
-// ListLPop 从左侧弹出列表元素
-func (rc *redisClient) ListLPop(key string) (string, error) {
-	value, err := rc.client.LPop(rc.ctx, key).Result()
-	if err == redis.Nil {
-		return "", fmt.Errorf("列表 %s 为空", key)
-	} else if err != nil {
-		return "", fmt.Errorf("弹出列表元素失败: %v", err)
+func (h closedHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if h.closed.Load() {
+			return ErrClientClosed
+		}
+		return next(ctx, cmds)
 	}
-	log.Printf("列表元素弹出成功: %s -> %s", key, value)
-	return value, nil
 }
 
-// ListLRange 获取列表指定范围的元素[start, stop]
-func (rc *redisClient) ListLRange(key string, start, stop int64) ([]string, error) {
-	items, err := rc.client.LRange(rc.ctx, key, start, stop).Result()
-	if err != nil {
-		return nil, fmt.Errorf("获取列表元素失败: %v", err)
+// keyValidationHook 在发送命令前检查键名，包含空白或控制字符时返回ErrInvalidKey
+type keyValidationHook struct{}
+
+func (keyValidationHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (keyValidationHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := validateCmdKeys(cmd); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
 	}
-	log.Printf("列表元素: %v", items)
-	return items, nil
 }
 
-// SetSAdd 添加元素到集合
-func (rc *redisClient) SetSAdd(key string, members ...interface{}) error {
-	err := rc.client.SAdd(rc.ctx, key, members...).Err()
-	if err != nil {
-		return fmt.Errorf("添加集合元素失败: %v", err)
+func (keyValidationHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		// 任意一条命令的键名不合法时整个pipeline都不发送
+		for _, cmd := range cmds {
+			if err := validateCmdKeys(cmd); err != nil {
+				cmd.SetErr(err)
+				return err
+			}
+		}
+		return next(ctx, cmds)
 	}
-	log.Printf("集合元素添加成功: %s -> %v", key, members)
-	return nil
 }
 
-// SetSRem 移除集合中的元素
-func (rc *redisClient) SetSRem(key string, members ...interface{}) error {
-	err := rc.client.SRem(rc.ctx, key, members...).Err()
-	if err != nil {
-		return fmt.Errorf("移除集合元素失败: %v", err)
+// validateCmdKeys 检查命令中的键名是否包含空白或控制字符
+func validateCmdKeys(cmd redis.Cmder) error {
+	for _, key := range cmdKeys(cmd) {
+		if strings.IndexFunc(key, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
 	}
-	log.Printf("集合元素移除成功: %s -> %v", key, members)
 	return nil
 }
 
-// SetSMembers 获取集合所有元素
-func (rc *redisClient) SetSMembers(key string) ([]string, error) {
-	members, err := rc.client.SMembers(rc.ctx, key).Result()
-	if err != nil {
-		return nil, fmt.Errorf("获取集合元素失败: %v", err)
+// cmdKeys 返回命令中的键名，按命令的参数格式取出全部键:
+//   - EVAL/EVALSHA/FCALL等脚本命令取numkeys之后的KEYS部分
+//   - SINTERCARD/LMPOP/ZMPOP/ZUNION/ZINTER/ZDIFF取numkeys之后的键，BLMPOP/BZMPOP跳过timeout后同样处理，
+//     ZUNIONSTORE/ZINTERSTORE/ZDIFFSTORE取目标键和numkeys之后的键
+//   - DEL/MGET/SDIFFSTORE/SUNION/PFMERGE等多键命令取全部参数，BLPOP/BZPOPMIN等阻塞命令取除最后的timeout外的全部参数
+//   - RENAME/COPY/SMOVE/LMOVE等取前两个参数，MSET/MSETNX取奇数位参数
+//   - OBJECT和MEMORY USAGE、DEBUG OBJECT取子命令后的参数，其余DEBUG子命令没有键
+//   - 其他命令取第一个参数
+//
+// 以下键不会被取出，因此不经过键名校验且不会出现在审计回调中: XREAD/XREADGROUP的STREAMS部分(只取第一个参数)、
+// SORT/GEORADIUS等命令STORE选项指定的目标键、MIGRATE的KEYS部分，以及上面没有列出的其他多键命令中第一个之后的键
+func cmdKeys(cmd redis.Cmder) []string {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return nil
+	}
+	var keys []interface{}
+	switch cmd.Name() {
+	case "eval", "evalsha", "eval_ro", "evalsha_ro", "fcall", "fcall_ro":
+		keys = numKeysArgs(args, 2)
+	case "sintercard", "lmpop", "zmpop", "zunion", "zinter", "zdiff":
+		keys = numKeysArgs(args, 1)
+	case "blmpop", "bzmpop":
+		keys = numKeysArgs(args, 2)
+	case "zunionstore", "zinterstore", "zdiffstore":
+		keys = append(args[1:2:2], numKeysArgs(args, 2)...)
+	case "del", "unlink", "exists", "touch", "mget", "watch",
+		"sdiff", "sinter", "sunion", "sdiffstore", "sinterstore", "sunionstore",
+		"pfcount", "pfmerge":
+		keys = args[1:]
+	case "blpop", "brpop", "bzpopmin", "bzpopmax":
+		keys = args[1 : len(args)-1]
+	case "rename", "renamenx", "copy", "smove", "lmove", "blmove", "rpoplpush", "brpoplpush":
+		if len(args) < 3 {
+			return nil
+		}
+		keys = args[1:3]
+	case "mset", "msetnx":
+		for i := 1; i < len(args); i += 2 {
+			keys = append(keys, args[i])
+		}
+	case "object":
+		keys = subcommandKey(args, "")
+	case "memory":
+		keys = subcommandKey(args, "usage")
+	case "debug":
+		keys = subcommandKey(args, "object")
+	default:
+		keys = args[1:2]
 	}
-	log.Printf("集合所有元素: %v", members)
-	return members, nil
-}
 
-// SetSIsMember 检查元素是否在集合中
-func (rc *redisClient) SetSIsMember(key string, member interface{}) (bool, error) {
-	isMember, err := rc.client.SIsMember(rc.ctx, key, member).Result()
-	if err != nil {
-		return false, fmt.Errorf("检查集合元素失败: %v", err)
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if name, ok := key.(string); ok {
+			names = append(names, name)
+		}
 	}
-	log.Printf("元素 %v 是否在集合 %s 中: %t", member, key, isMember)
-	return isMember, nil
+	return names
 }
 
-// SetSCard 获取集合元素数量
-func (rc *redisClient) SetSCard(key string) (int64, error) {
-	cardinality, err := rc.client.SCard(rc.ctx, key).Result()
-	if err != nil {
-		return 0, fmt.Errorf("获取集合元素数量失败: %v", err)
+// subcommandKey 返回子命令之后的第一个参数作为键，sub不为空时只有子命令为sub(不区分大小写)才返回
+func subcommandKey(args []interface{}, sub string) []interface{} {
+	if len(args) < 3 || (sub != "" && !strings.EqualFold(fmt.Sprint(args[1]), sub)) {
+		return nil
 	}
-	log.Printf("集合元素数量: %d", cardinality)
-	return cardinality, nil
+	return args[2:3]
 }
 
-// SetSRandMember 随机获取集合中的一个元素
-func (rc *redisClient) SetSRandMember(key string) (string, error) {
-	randomMember, err := rc.client.SRandMember(rc.ctx, key).Result()
-	if err != nil {
-		return "", fmt.Errorf("随机获取集合元素失败: %v", err)
+// numKeysArgs 返回args[i]指定数量的、紧跟在args[i]之后的键，numkeys不合法时返回nil
+func numKeysArgs(args []interface{}, i int) []interface{} {
+	if len(args) <= i {
+		return nil
 	}
-	log.Printf("随机获取的元素: %s", randomMember)
-	return randomMember, nil
+	n, err := strconv.Atoi(fmt.Sprint(args[i]))
+	if err != nil || n < 0 || i+1+n > len(args) {
+		return nil
+	}
+	return args[i+1 : i+1+n]
 }
 
-// SetZAdd 添加/更新有序集合中的元素（带分数）
-func (rc *redisClient) SetZAdd(key string, members ...redis.Z) error {
-	err := rc.client.ZAdd(rc.ctx, key, members...).Err()
-	if err != nil {
-		return fmt.Errorf("添加/更新有序集合元素失败: %v", err)
-	}
-	log.Printf("有序集合元素添加/更新成功: %s -> %v", key, members)
-	return nil
+// wrongTypeHook 将Redis返回的WRONGTYPE错误包装为ErrWrongType，便于使用errors.Is判断
+type wrongTypeHook struct{}
+
+func (wrongTypeHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
 }
 
-// SetZRem 移除有序集合中的元素
-func (rc *redisClient) SetZRem(key string, members ...interface{}) error {
-	err := rc.client.ZRem(rc.ctx, key, members...).Err()
-	if err != nil {
-		return fmt.Errorf("移除有序集合元素失败: %v", err)
+func (wrongTypeHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		return wrapWrongType(next(ctx, cmd))
 	}
-	log.Printf("有序集合元素移除成功: %s -> %v", key, members)
-	return nil
 }
 
-// SetZRange 获取有序集合指定范围的元素(按分数升序) [start, stop]
-func (rc *redisClient) SetZRange(key string, start, stop int64) ([]string, error) {
-	members, err := rc.client.ZRange(rc.ctx, key, start, stop).Result()
-	if err != nil {
-		return nil, fmt.Errorf("获取有序集合元素失败: %v", err)
+func (wrongTypeHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			if cmdErr := cmd.Err(); cmdErr != nil {
+				cmd.SetErr(wrapWrongType(cmdErr))
+			}
+		}
+		return wrapWrongType(err)
 	}
-	log.Printf("有序集合所有元素（按分数升序）: %v", members)
-	return members, nil
 }
 
-// SetZRevRange 获取有序集合指定范围的元素(按分数降序) [start, stop]
-func (rc *redisClient) SetZRevRange(key string, start, stop int64) ([]string, error) {
-	members, err := rc.client.ZRevRange(rc.ctx, key, start, stop).Result()
-	if err != nil {
-		return nil, fmt.Errorf("获取有序集合元素失败: %v", err)
+// wrapWrongType 若错误以WRONGTYPE开头，则包装为ErrWrongType
+func wrapWrongType(err error) error {
+	if err != nil && !errors.Is(err, ErrWrongType) && strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		return fmt.Errorf("%w: %v", ErrWrongType, err)
 	}
-	log.Printf("有序集合所有元素（按分数降序）: %v", members)
-	return members, nil
+	return err
 }
 
-// SetZCard 获取有序集合元素数量
-func (rc *redisClient) SetZCard(key string) (int64, error) {
-	cardinality, err := rc.client.ZCard(rc.ctx, key).Result()
-	if err != nil {
-		return 0, fmt.Errorf("获取有序集合元素数量失败: %v", err)
+// opTimeoutKey 在context中保存单次操作超时时间的键
+type opTimeoutKey struct{}
+
+// timeoutHook 根据context中保存的超时时间，为每条命令派生带超时的context
+type timeoutHook struct{}
+
+func (timeoutHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (timeoutHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, cancel := withOpTimeout(ctx)
+		defer cancel()
+		return next(ctx, cmd)
 	}
-	log.Printf("有序集合元素数量: %d", cardinality)
-	return cardinality, nil
 }
 
-// SetZRangeByScore 获取有序集合指定分数范围内的元素(按分数升序) [min, max] [start, stop]
-func (rc *redisClient) SetZRangeByScore(key string, min, max string, start, stop int64) ([]string, error) {
-	if min > max {
-		return nil, fmt.Errorf("min 必须小于等于 max")
+func (timeoutHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, cancel := withOpTimeout(ctx)
+		defer cancel()
+		return next(ctx, cmds)
 	}
+}
 
-	members, err := rc.client.ZRangeByScore(rc.ctx, key, &redis.ZRangeBy{
-		Min:    min,
-		Max:    max,
-		Offset: start,
-		Count:  stop - start + 1,
-	}).Result()
-	if err != nil {
-		return nil, fmt.Errorf("获取有序集合元素失败: %v", err)
+// withOpTimeout 若context中设置了单次操作超时时间，则派生带超时的context
+func withOpTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d, ok := ctx.Value(opTimeoutKey{}).(time.Duration); ok && d > 0 {
+		return context.WithTimeout(ctx, d)
 	}
-	log.Printf("有序集合，在 %s 到 %s 分数，%d 到 %d 范围内的所有元素（按分数升序）: %v", min, max, start, stop, members)
-	return members, nil
+	return ctx, func() {}
 }
 
-// SetZRevRangeByScore 获取有序集合指定分数范围内的元素(按分数降序) [min, max] [start, stop]
-func (rc *redisClient) SetZRevRangeByScore(key string, min, max string, start, stop int64) ([]string, error) {
-	if min > max {
-		return nil, fmt.Errorf("min 必须小于等于 max")
+// opHook 在每条命令执行前后触发审计回调
+// 多键命令对每个键各触发一次，没有键的命令以空键名触发；事务的MULTI/EXEC不触发回调
+type opHook struct {
+	before func(op, key string)
+	after  func(op, key string, err error)
+}
+
+func (h opHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h opHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.beforeCmd(cmd)
+		err := next(ctx, cmd)
+		h.afterCmd(cmd, err)
+		return err
 	}
+}
 
-	members, err := rc.client.ZRevRangeByScore(rc.ctx, key, &redis.ZRangeBy{
-		Min:    min,
-		Max:    max,
-		Offset: start,
-		Count:  stop - start + 1,
-	}).Result()
-	if err != nil {
-		return nil, fmt.Errorf("获取有序集合元素失败: %v", err)
+func (h opHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			h.beforeCmd(cmd)
+		}
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			h.afterCmd(cmd, cmd.Err())
+		}
+		return err
 	}
-	log.Printf("有序集合，在 %s 到 %s 分数，%d 到 %d 范围内的所有元素（按分数降序）: %v", min, max, start, stop, members)
-	return members, nil
 }
 
-// SetZScore 获取有序集合中元素的分数
-func (rc *redisClient) SetZScore(key string, member string) error {
-	score, err := rc.client.ZScore(rc.ctx, key, member).Result()
-	if err != nil {
-		return fmt.Errorf("获取元素分数失败: %v", err)
+func (h opHook) beforeCmd(cmd redis.Cmder) {
+	if h.before == nil || isTxWrapper(cmd) {
+		return
+	}
+	for _, key := range opKeys(cmd) {
+		h.before(cmdOp(cmd), key)
 	}
-	log.Printf("元素 %s 的分数为 %f", member, score)
-	return nil
 }
 
-// SetZIncrBy 增加有序集合中元素的分数
-func (rc *redisClient) SetZIncrBy(key string, member string, increment float64) error {
-	newScore, err := rc.client.ZIncrBy(rc.ctx, key, increment, member).Result()
-	if err != nil {
-		return fmt.Errorf("增加元素分数失败: %v", err)
+func (h opHook) afterCmd(cmd redis.Cmder, err error) {
+	if h.after == nil || isTxWrapper(cmd) {
+		return
+	}
+	if err == redis.Nil {
+		err = nil
+	}
+	// 脚本未缓存时go-redis会以EVAL重新执行并再次触发回调，不把这次NOSCRIPT失败报告给审计
+	if cmd.Name() == "evalsha" && redis.HasErrorPrefix(err, "NOSCRIPT") {
+		return
+	}
+	for _, key := range opKeys(cmd) {
+		h.after(cmdOp(cmd), key, err)
 	}
-	log.Printf("元素 %s 的分数增加为 %f", member, newScore)
-	return nil
 }
 
-// SetZRank 获取有序集合中元素的排名（按分数升序）
-func (rc *redisClient) SetZRank(key string, member string) error {
-	rank, err := rc.client.ZRank(rc.ctx, key, member).Result()
-	if err != nil {
-		return fmt.Errorf("获取元素排名失败: %v", err)
+// cmdOp 返回命令名称(大写)，如"SET"
+func cmdOp(cmd redis.Cmder) string {
+	return strings.ToUpper(cmd.Name())
+}
+
+// opKeys 返回审计回调使用的键名，没有键的命令(如PING)返回一个空键名
+func opKeys(cmd redis.Cmder) []string {
+	if keys := cmdKeys(cmd); len(keys) > 0 {
+		return keys
 	}
-	log.Printf("元素 %s 的排名为 %d(按分数升序)", member, rank)
-	return nil
+	return []string{""}
 }
 
-// SetZRevRank 获取有序集合中元素的排名（按分数降序）
-func (rc *redisClient) SetZRevRank(key string, member string) error {
-	rank, err := rc.client.ZRevRank(rc.ctx, key, member).Result()
-	if err != nil {
-		return fmt.Errorf("获取元素排名失败: %v", err)
+// isTxWrapper 判断命令是否为事务pipeline自动添加的MULTI/EXEC
+func isTxWrapper(cmd redis.Cmder) bool {
+	name := cmd.Name()
+	return name == "multi" || name == "exec"
+}
+
+// tracingHook 为每条命令创建名为"redis.<命令>"的span，pipeline整体创建名为"redis.PIPELINE"的span
+type tracingHook struct {
+	tracer Tracer
+}
+
+func (h tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, end := h.tracer.StartSpan(ctx, "redis."+cmdOp(cmd))
+		err := next(ctx, cmd)
+		end(spanErr(err))
+		return err
 	}
-	log.Printf("元素 %s 的排名为 %d(按分数降序)", member, rank)
-	return nil
 }
 
-// SetHashSet 设置哈希字段
-func (rc *redisClient) HashSet(hashKey string, values ...interface{}) error {
-	err := rc.client.HSet(rc.ctx, hashKey, values...).Err()
-	if err != nil {
-		return fmt.Errorf("设置哈希字段失败: %v", err)
+func (h tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, end := h.tracer.StartSpan(ctx, "redis.PIPELINE")
+		err := next(ctx, cmds)
+		end(spanErr(err))
+		return err
 	}
-	log.Printf("哈希字段 %s 设置成功: %v", hashKey, values)
-	return nil
 }
 
-// SetHashGetAll 获取哈希字段的所有值
-func (rc *redisClient) HashGetAll(hashKey string) (map[string]string, error) {
-	fields, err := rc.client.HGetAll(rc.ctx, hashKey).Result()
-	if err != nil {
-		return nil, fmt.Errorf("获取哈希字段失败: %v", err)
+// spanErr 键不存在(redis.Nil)不视为span的错误
+func spanErr(err error) error {
+	if err == redis.Nil {
+		return nil
 	}
-	log.Printf("哈希字段: %v", fields)
-	return fields, nil
+	return err
 }
 
-// SetHashGet 获取哈希字段的值
-func (rc *redisClient) HashGet(hashKey string, field string) (string, error) {
-	value, err := rc.client.HGet(rc.ctx, hashKey, field).Result()
-	if err != nil {
-		return "", fmt.Errorf("获取哈希字段失败: %v", err)
+// idempotentCommands 可以安全重试的只读/幂等命令
+var idempotentCommands = map[string]bool{
+	"get": true, "exists": true, "type": true, "ttl": true, "pttl": true,
+	"llen": true, "lrange": true,
+	"smembers": true, "sismember": true, "scard": true, "srandmember": true,
+	"zrange": true, "zrevrange": true, "zcard": true, "zrangebyscore": true, "zrevrangebyscore": true,
+	"zscore": true, "zrank": true, "zrevrank": true, "zunion": true, "zinter": true,
+	"hget": true, "hgetall": true,
+	"ping": true,
+}
+
+// debugReplyMaxLen 调试日志中单条命令及回复的最大长度，超出部分被截断
+const debugReplyMaxLen = 256
+
+// debugHook 记录实际发送的每条命令的参数和回复，最后添加因此重试时每次尝试都会记录
+type debugHook struct{}
+
+func (debugHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (debugHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		logCommand(cmd)
+		return err
 	}
-	log.Printf("哈希字段 %s 的值为 %s", field, value)
-	return value, nil
 }
 
-// Close 关闭Redis连接
+func (debugHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			logCommand(cmd)
+		}
+		return err
+	}
+}
+
+// logCommand 以"命令 参数: 回复"的格式输出命令，如"set key value: OK"，命令出错时附带错误
+func logCommand(cmd redis.Cmder) {
+	line := cmd.String()
+	if len(line) > debugReplyMaxLen {
+		line = line[:debugReplyMaxLen] + "...(截断)"
+	}
+	if err := cmd.Err(); err != nil {
+		log.Printf("[redis] %s (错误: %v)", line, err)
+		return
+	}
+	log.Printf("[redis] %s", line)
+}
+
+// retryHook 对只读/幂等命令按重试策略进行重试
+type retryHook struct {
+	policy *RetryPolicy
+}
+
+func (h retryHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h retryHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if !idempotentCommands[cmd.Name()] {
+			return next(ctx, cmd)
+		}
+
+		for attempt := 0; ; attempt++ {
+			err := next(ctx, cmd)
+			if !shouldRetry(err) || attempt >= h.policy.Attempts-1 {
+				return err
+			}
+
+			delay := h.policy.backoff(attempt)
+			log.Printf("命令 %s 执行失败，%v 后重试(第 %d 次): %v", cmdOp(cmd), delay, attempt+1, err)
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+func (h retryHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+// shouldRetry 判断错误是否可以重试：键不存在、客户端已关闭、context取消以及Redis服务端返回的错误均不重试
+func shouldRetry(err error) bool {
+	if err == nil || err == redis.Nil || err == redis.ErrClosed || errors.Is(err, ErrWrongType) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var redisErr redis.Error
+	return !errors.As(err, &redisErr)
+}
+
+// WithTimeout 返回为每次操作单独设置超时时间的客户端
+// 每条命令都会从基础context派生一个超时为d的context，原客户端不受影响
+func (rc *redisClient) WithTimeout(d time.Duration) RedisClient {
+	clone := *rc
+	clone.ctx = context.WithValue(rc.ctx, opTimeoutKey{}, d)
+	return &clone
+}
+
+// WithContext 返回使用指定context的客户端浅拷贝，便于传递请求级的超时、取消和链路追踪信息
+// 拷贝与原客户端共享同一个连接池
+func (rc *redisClient) WithContext(ctx context.Context) *redisClient {
+	clone := *rc
+	clone.ctx = ctx
+	return &clone
+}
+
+// Set 设置键值对
+func (rc *redisClient) Set(key, value string, expiration time.Duration) error {
+	if err := rc.checkValueSize(key, len(value)); err != nil {
+		return err
+	}
+	err := rc.client().Set(rc.ctx, key, value, expiration).Err()
+	if err != nil {
+		return fmt.Errorf("设置键值对失败: %w", err)
+	}
+	log.Printf("设置成功: %s -> %s", key, value)
+	return nil
+}
+
+// checkValueSize 配置了MaxValueBytes时检查要写入的值的大小，超过上限时返回ErrValueTooLarge
+func (rc *redisClient) checkValueSize(key string, size int) error {
+	if rc.config.MaxValueBytes > 0 && size > rc.config.MaxValueBytes {
+		return fmt.Errorf("%w: 键 %s 的值为 %d 字节，上限为 %d 字节", ErrValueTooLarge, key, size, rc.config.MaxValueBytes)
+	}
+	return nil
+}
+
+// Get 获取键的值
+// 配置了SlidingTTL时使用GETEX在读取的同时重置过期时间
+func (rc *redisClient) Get(key string) (string, error) {
+	var cmd *redis.StringCmd
+	if rc.config.SlidingTTL > 0 {
+		cmd = rc.client().GetEx(rc.ctx, key, rc.config.SlidingTTL)
+	} else {
+		cmd = rc.client().Get(rc.ctx, key)
+	}
+	value, err := cmd.Result()
+	if err == redis.Nil {
+		return "", fmt.Errorf("键不存在: %s", key)
+	} else if err != nil {
+		return "", fmt.Errorf("获取键值失败: %w", err)
+	}
+	log.Printf("获取成功: %s -> %s", key, value)
+	return value, nil
+}
+
+// GetInt 获取键的值并解析为整数，键不存在时返回与Get相同的错误，解析失败时返回的错误包装了*strconv.NumError
+func (rc *redisClient) GetInt(key string) (int64, error) {
+	value, err := rc.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("键 %s 的值 %q 不是整数: %w", key, value, err)
+	}
+	return n, nil
+}
+
+// GetFloat 获取键的值并解析为浮点数，错误规则与GetInt相同
+func (rc *redisClient) GetFloat(key string) (float64, error) {
+	value, err := rc.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("键 %s 的值 %q 不是浮点数: %w", key, value, err)
+	}
+	return f, nil
+}
+
+// GetBool 获取键的值并解析为布尔值，支持"1"/"0"、"true"/"false"等格式，错误规则与GetInt相同
+func (rc *redisClient) GetBool(key string) (bool, error) {
+	value, err := rc.Get(key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("键 %s 的值 %q 不是布尔值: %w", key, value, err)
+	}
+	return b, nil
+}
+
+// Delete 删除键
+func (rc *redisClient) Delete(key string) error {
+	err := rc.client().Del(rc.ctx, key).Err()
+	if err != nil {
+		return fmt.Errorf("删除键失败: %w", err)
+	}
+	log.Printf("删除成功: %s", key)
+	return nil
+}
+
+// DeleteReport 批量删除键，返回实际存在并被删除的键
+// 在同一个pipeline中对每个键单独执行DEL，根据各自的返回值判断键是否存在
+func (rc *redisClient) DeleteReport(keys ...string) ([]string, error) {
+	cmds := make([]*redis.IntCmd, len(keys))
+	_, err := rc.client().Pipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Del(rc.ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("批量删除键失败: %w", err)
+	}
+
+	deleted := make([]string, 0, len(keys))
+	for i, cmd := range cmds {
+		if cmd.Val() > 0 {
+			deleted = append(deleted, keys[i])
+		}
+	}
+	log.Printf("批量删除成功: %v", deleted)
+	return deleted, nil
+}
+
+// DeleteByPattern 使用SCAN遍历匹配模式的键，每累计batchSize个键执行一次UNLINK，返回删除的键数量
+// batchSize越大往返次数越少，但单次UNLINK处理的键越多
+func (rc *redisClient) DeleteByPattern(pattern string, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	var deleted int64
+	batch := make([]string, 0, batchSize)
+	unlink := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := rc.client().Unlink(rc.ctx, batch...).Result()
+		if err != nil {
+			return fmt.Errorf("批量删除键失败: %w", err)
+		}
+		deleted += n
+		batch = batch[:0]
+		return nil
+	}
+
+	err := rc.ScanEach(pattern, int64(batchSize), func(key string) error {
+		batch = append(batch, key)
+		if len(batch) >= batchSize {
+			return unlink()
+		}
+		return nil
+	})
+	if err == nil {
+		err = unlink()
+	}
+	if err != nil {
+		return deleted, err
+	}
+	log.Printf("删除匹配 %s 的键: %d 个", pattern, deleted)
+	return deleted, nil
+}
+
+// SweepIdle 使用SCAN遍历匹配模式的键，删除空闲时间(OBJECT IDLETIME，秒级精度)超过idleThreshold的键，返回删除的数量
+// 适用于清理写入时没有设置过期时间的旧键；每批键的空闲时间在同一个pipeline中查询，要求maxmemory-policy不是LFU
+func (rc *redisClient) SweepIdle(pattern string, idleThreshold time.Duration) (int64, error) {
+	const batchSize = 100
+	var deleted int64
+	batch := make([]string, 0, batchSize)
+	sweep := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		cmds := make([]*redis.DurationCmd, len(batch))
+		_, err := rc.client().Pipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range batch {
+				cmds[i] = pipe.ObjectIdleTime(rc.ctx, key)
+			}
+			return nil
+		})
+		if err != nil && err != redis.Nil {
+			return fmt.Errorf("获取键的空闲时间失败: %w", err)
+		}
+
+		idle := make([]string, 0, len(batch))
+		for i, cmd := range cmds {
+			// 遍历期间被删除的键返回redis.Nil，直接跳过
+			if cmd.Err() == nil && cmd.Val() > idleThreshold {
+				idle = append(idle, batch[i])
+			}
+		}
+		batch = batch[:0]
+		if len(idle) == 0 {
+			return nil
+		}
+		n, err := rc.client().Unlink(rc.ctx, idle...).Result()
+		if err != nil {
+			return fmt.Errorf("批量删除键失败: %w", err)
+		}
+		deleted += n
+		return nil
+	}
+
+	err := rc.ScanEach(pattern, batchSize, func(key string) error {
+		batch = append(batch, key)
+		if len(batch) >= batchSize {
+			return sweep()
+		}
+		return nil
+	})
+	if err == nil {
+		err = sweep()
+	}
+	if err != nil {
+		return deleted, err
+	}
+	log.Printf("删除匹配 %s 且空闲超过 %v 的键: %d 个", pattern, idleThreshold, deleted)
+	return deleted, nil
+}
+
+// Exists 检查键是否存在
+func (rc *redisClient) Exists(key string) (bool, error) {
+	result, err := rc.client().Exists(rc.ctx, key).Result()
+	if err != nil {
+		return false, fmt.Errorf("检查键存在失败: %w", err)
+	}
+	exists := result > 0
+	log.Printf("键 %s 存在: %v, result: %v", key, exists, result)
+	return exists, nil
+}
+
+// BatchExists 在同一个pipeline中批量检查多个键是否存在，返回每个键的检查结果
+func (rc *redisClient) BatchExists(keys ...string) (map[string]bool, error) {
+	cmds := make([]*redis.IntCmd, len(keys))
+	_, err := rc.client().Pipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Exists(rc.ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("批量检查键存在失败: %w", err)
+	}
+
+	exists := make(map[string]bool, len(keys))
+	for i, cmd := range cmds {
+		exists[keys[i]] = cmd.Val() > 0
+	}
+	log.Printf("批量检查键存在: %v", exists)
+	return exists, nil
+}
+
+// ExpireWithFlag 按条件设置键的过期时间(需Redis 7.0+)，返回是否设置成功
+// flag: NX-仅当键没有过期时间时设置, XX-仅当键已有过期时间时设置,
+// GT-仅当新过期时间大于当前过期时间时设置, LT-仅当新过期时间小于当前过期时间时设置
+func (rc *redisClient) ExpireWithFlag(key string, ttl time.Duration, flag string) (bool, error) {
+	var cmd *redis.BoolCmd
+	switch strings.ToUpper(flag) {
+	case "NX":
+		cmd = rc.client().ExpireNX(rc.ctx, key, ttl)
+	case "XX":
+		cmd = rc.client().ExpireXX(rc.ctx, key, ttl)
+	case "GT":
+		cmd = rc.client().ExpireGT(rc.ctx, key, ttl)
+	case "LT":
+		cmd = rc.client().ExpireLT(rc.ctx, key, ttl)
+	default:
+		return false, fmt.Errorf("不支持的过期条件: %s", flag)
+	}
+
+	ok, err := cmd.Result()
+	if err != nil {
+		return false, fmt.Errorf("设置过期时间失败: %w", err)
+	}
+	log.Printf("按条件 %s 设置过期时间: %s (过期时间: %v, 是否设置: %t)", flag, key, ttl, ok)
+	return ok, nil
+}
+
+// PersistMany 在同一个pipeline中批量移除多个键的过期时间，返回每个键是否确实移除了过期时间
+// 键不存在或本身没有过期时间时结果为false
+func (rc *redisClient) PersistMany(keys ...string) (map[string]bool, error) {
+	cmds := make([]*redis.BoolCmd, len(keys))
+	_, err := rc.client().Pipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Persist(rc.ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("批量移除过期时间失败: %w", err)
+	}
+
+	persisted := make(map[string]bool, len(keys))
+	for i, cmd := range cmds {
+		persisted[keys[i]] = cmd.Val()
+	}
+	log.Printf("批量移除过期时间: %v", persisted)
+	return persisted, nil
+}
+
+// SetWithExpire 设置带过期时间的键值对
+func (rc *redisClient) SetWithExpire(key, value string, expiration time.Duration) error {
+	if err := rc.checkValueSize(key, len(value)); err != nil {
+		return err
+	}
+	err := rc.client().SetEx(rc.ctx, key, value, expiration).Err()
+	if err != nil {
+		return fmt.Errorf("设置带过期时间的键值对失败: %w", err)
+	}
+	log.Printf("设置带过期时间成功: %s -> %s (过期时间: %v)", key, value, expiration)
+	return nil
+}
+
+// encodeValue 编码写入的值: string和[]byte原样保存，其他类型使用JSON编码
+func encodeValue(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// decodeValue 按dest的类型解码读取的值: *string和*[]byte直接赋值，其他类型使用JSON解码
+func decodeValue(data []byte, dest interface{}) error {
+	switch d := dest.(type) {
+	case *string:
+		*d = string(data)
+		return nil
+	case *[]byte:
+		*d = data
+		return nil
+	default:
+		return json.Unmarshal(data, dest)
+	}
+}
+
+// SetAny 设置任意类型的值，string和[]byte原样保存，其他类型使用JSON编码后保存
+func (rc *redisClient) SetAny(key string, value interface{}, ttl time.Duration) error {
+	data, err := encodeValue(value)
+	if err != nil {
+		return fmt.Errorf("编码键 %s 的值失败: %w", key, err)
+	}
+	if err := rc.checkValueSize(key, len(data)); err != nil {
+		return err
+	}
+	err = rc.client().Set(rc.ctx, key, data, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("设置键值对失败: %w", err)
+	}
+	log.Printf("设置成功: %s -> %v", key, value)
+	return nil
+}
+
+// SetBytes 原样写入二进制值，等同于以[]byte调用SetAny
+func (rc *redisClient) SetBytes(key string, value []byte, ttl time.Duration) error {
+	return rc.SetAny(key, value, ttl)
+}
+
+// GetAny 获取键的值并按dest的类型解码，dest必须为指针
+// dest为*string或*[]byte时直接返回原始值，否则按JSON解码，与SetAny的编码方式对应
+func (rc *redisClient) GetAny(key string, dest interface{}) error {
+	data, err := rc.client().Get(rc.ctx, key).Bytes()
+	if err == redis.Nil {
+		return fmt.Errorf("键不存在: %s", key)
+	} else if err != nil {
+		return fmt.Errorf("获取键值失败: %w", err)
+	}
+	if err := decodeValue(data, dest); err != nil {
+		return fmt.Errorf("解码键 %s 的值失败: %w", key, err)
+	}
+	log.Printf("获取成功: %s -> %s", key, data)
+	return nil
+}
+
+// compressMagic SetObject写入压缩值时添加的头部，后面紧跟gzip数据，用于区分压缩和未压缩的值
+// 以0字节开头，不会与JSON和普通文本冲突；不直接使用gzip自身的头部，避免恰好以0x1f 0x8b开头的原始字节被误判为压缩值
+var compressMagic = []byte("\x00rgz1")
+
+// compressValue 使用gzip压缩数据并添加compressMagic头部
+func compressValue(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(compressMagic)
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressValue 数据以compressMagic开头时解压，否则原样返回，兼容未压缩的旧值
+func decompressValue(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, compressMagic) {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data[len(compressMagic):]))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// SetObject 按SetAny的规则编码对象后写入，配置了Compress时先用gzip压缩
+func (rc *redisClient) SetObject(key string, value interface{}, ttl time.Duration) error {
+	data, err := encodeValue(value)
+	if err != nil {
+		return fmt.Errorf("编码键 %s 的值失败: %w", key, err)
+	}
+	if rc.config.Compress {
+		if data, err = compressValue(data); err != nil {
+			return fmt.Errorf("压缩键 %s 的值失败: %w", key, err)
+		}
+	}
+	// 按压缩后实际写入的大小检查，压缩后不超过上限的大对象也可以写入
+	if err := rc.checkValueSize(key, len(data)); err != nil {
+		return err
+	}
+	err = rc.client().Set(rc.ctx, key, data, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("设置键值对失败: %w", err)
+	}
+	log.Printf("设置对象成功: %s (%d 字节)", key, len(data))
+	return nil
+}
+
+// GetObject 读取SetObject写入的对象并解码到dest，dest必须为指针
+// 值以compressMagic开头时先解压，因此可以读取开启Compress前写入的未压缩值
+func (rc *redisClient) GetObject(key string, dest interface{}) error {
+	data, err := rc.client().Get(rc.ctx, key).Bytes()
+	if err == redis.Nil {
+		return fmt.Errorf("键不存在: %s", key)
+	} else if err != nil {
+		return fmt.Errorf("获取键值失败: %w", err)
+	}
+	if data, err = decompressValue(data); err != nil {
+		return fmt.Errorf("解压键 %s 的值失败: %w", key, err)
+	}
+	if err := decodeValue(data, dest); err != nil {
+		return fmt.Errorf("解码键 %s 的值失败: %w", key, err)
+	}
+	log.Printf("获取对象成功: %s (%d 字节)", key, len(data))
+	return nil
+}
+
+// SetEntry 批量写入的键值对及其过期时间，TTL为0表示不过期
+type SetEntry struct {
+	Key   string
+	Value string
+	TTL   time.Duration
+}
+
+// SetManyWithTTL 在同一个pipeline中对每个键执行SET，每个键使用各自的过期时间
+// 任意一个值超过MaxValueBytes时不写入任何键
+func (rc *redisClient) SetManyWithTTL(entries []SetEntry) error {
+	for _, entry := range entries {
+		if err := rc.checkValueSize(entry.Key, len(entry.Value)); err != nil {
+			return err
+		}
+	}
+	_, err := rc.client().Pipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		for _, entry := range entries {
+			pipe.Set(rc.ctx, entry.Key, entry.Value, entry.TTL)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("批量设置键值对失败: %w", err)
+	}
+	log.Printf("批量设置成功: %d 个键", len(entries))
+	return nil
+}
+
+// PipelineGet 在同一个pipeline中批量获取多个键的值，不存在的键不会出现在结果中
+// 适用于单节点部署；集群模式下键分布在不同槽位时无法在一次往返中完成
+func (rc *redisClient) PipelineGet(keys ...string) (map[string]string, error) {
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := rc.client().Pipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(rc.ctx, key)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("批量获取键值失败: %w", err)
+	}
+
+	values := make(map[string]string, len(keys))
+	for i, cmd := range cmds {
+		value, err := cmd.Result()
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("获取键 %s 的值失败: %w", keys[i], err)
+		}
+		values[keys[i]] = value
+	}
+	log.Printf("批量获取成功: %v", values)
+	return values, nil
+}
+
+// PipelineGetObjects 在同一个pipeline中批量获取多个键的值，并按GetObject的规则(先解压gzip再解码)解码到newDest分配的对象中
+// newDest需为每个键返回一个新的指针，例如func() interface{} { return new(User) }；不存在的键不会出现在结果中
+func (rc *redisClient) PipelineGetObjects(keys []string, newDest func() interface{}) (map[string]interface{}, error) {
+	values, err := rc.PipelineGet(keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		data, err := decompressValue([]byte(value))
+		if err != nil {
+			return nil, fmt.Errorf("解压键 %s 的值失败: %w", key, err)
+		}
+		dest := newDest()
+		if err := decodeValue(data, dest); err != nil {
+			return nil, fmt.Errorf("解码键 %s 的值失败: %w", key, err)
+		}
+		objects[key] = dest
+	}
+	return objects, nil
+}
+
+// setIfNewerScript 比较哈希中存储的version字段，仅当传入版本更大时写入value并设置过期时间
+var setIfNewerScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], 'version')
+if current and tonumber(current) >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'version', ARGV[1], 'value', ARGV[2])
+local ttl = tonumber(ARGV[3])
+if ttl > 0 then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+else
+	redis.call('PERSIST', KEYS[1])
+end
+return 1
+`)
+
+// SetIfNewer 仅当版本号比已存储的版本更新时才写入，返回是否写入
+// 键以哈希形式存储，version字段保存版本号，value字段保存值，ttl为0表示不过期
+func (rc *redisClient) SetIfNewer(key string, version int64, value string, ttl time.Duration) (bool, error) {
+	if err := rc.checkValueSize(key, len(value)); err != nil {
+		return false, err
+	}
+	written, err := setIfNewerScript.Run(rc.ctx, rc.client(), []string{key}, version, value, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("按版本写入失败: %w", err)
+	}
+	log.Printf("按版本写入: %s -> %s (版本: %d, 是否写入: %t)", key, value, version, written == 1)
+	return written == 1, nil
+}
+
+// setNXGetScript 键不存在时设置值并返回{1, 新值}，否则返回{0, 当前值}
+var setNXGetScript = redis.NewScript(`
+local ok
+if tonumber(ARGV[2]) > 0 then
+	ok = redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2])
+else
+	ok = redis.call('SET', KEYS[1], ARGV[1], 'NX')
+end
+if ok then
+	return {1, ARGV[1]}
+end
+return {0, redis.call('GET', KEYS[1])}
+`)
+
+// SetNXGet 键不存在时设置值并返回acquired=true，否则不修改并返回acquired=false及当前值
+// 适用于选主等"不存在则设置，否则告诉我当前值"的场景，ttl为0表示不过期
+func (rc *redisClient) SetNXGet(key, value string, ttl time.Duration) (bool, string, error) {
+	if err := rc.checkValueSize(key, len(value)); err != nil {
+		return false, "", err
+	}
+	result, err := setNXGetScript.Run(rc.ctx, rc.client(), []string{key}, value, ttl.Milliseconds()).Slice()
+	if err != nil {
+		return false, "", fmt.Errorf("设置键值对失败: %w", err)
+	}
+	if len(result) != 2 {
+		return false, "", fmt.Errorf("设置键值对返回结果异常: %v", result)
+	}
+	acquired, _ := result[0].(int64)
+	current, _ := result[1].(string)
+	log.Printf("不存在则设置: %s -> %s (是否设置: %t, 当前值: %s)", key, value, acquired == 1, current)
+	return acquired == 1, current, nil
+}
+
+// renameWithTTLScript 将KEYS[1]重命名为KEYS[2]并设置毫秒级过期时间
+var renameWithTTLScript = redis.NewScript(`
+redis.call('RENAME', KEYS[1], KEYS[2])
+return redis.call('PEXPIRE', KEYS[2], ARGV[1])
+`)
+
+// RenameWithTTL 将src重命名为dest(覆盖已有的dest)并设置过期时间，两步在同一个Lua脚本中原子执行
+// 适用于蓝绿切换: 在src上构建好新数据后一次性替换线上的dest；src不存在时返回错误，集群模式下两个键需在同一个slot
+func (rc *redisClient) RenameWithTTL(src, dest string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("ttl 必须大于 0")
+	}
+	err := renameWithTTLScript.Run(rc.ctx, rc.client(), []string{src, dest}, ttl.Milliseconds()).Err()
+	if err != nil {
+		return fmt.Errorf("重命名键失败: %w", err)
+	}
+	log.Printf("重命名成功: %s -> %s (过期时间: %v)", src, dest, ttl)
+	return nil
+}
+
+// Increment 对数字值进行递增
+func (rc *redisClient) Increment(key string) (int64, error) {
+	result, err := rc.client().Incr(rc.ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("递增操作失败: %w", err)
+	}
+	log.Printf("递增成功: %s -> %d", key, result)
+	return result, nil
+}
+
+// decrementFloorScript 仅当递减后的值不低于下限时执行DECRBY，返回{是否成功, 当前值}
+var decrementFloorScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if current - tonumber(ARGV[1]) < tonumber(ARGV[2]) then
+	return {0, current}
+end
+return {1, redis.call('DECRBY', KEYS[1], ARGV[1])}
+`)
+
+// DecrementFloor 将键的值减少by，若结果低于floor则不修改并返回ok=false，适用于库存扣减
+// 键不存在时视为0
+func (rc *redisClient) DecrementFloor(key string, by int64, floor int64) (int64, bool, error) {
+	result, err := decrementFloorScript.Run(rc.ctx, rc.client(), []string{key}, by, floor).Int64Slice()
+	if err != nil {
+		return 0, false, fmt.Errorf("递减操作失败: %w", err)
+	}
+	if len(result) != 2 {
+		return 0, false, fmt.Errorf("递减操作返回结果异常: %v", result)
+	}
+	ok := result[0] == 1
+	log.Printf("递减操作: %s -> %d (递减: %d, 下限: %d, 是否成功: %t)", key, result[1], by, floor, ok)
+	return result[1], ok, nil
+}
+
+// GetRangeInt 读取字符串中[start, end]字节范围(GETRANGE，包含两端，支持负数下标)的内容并按十进制解析为整数
+// 适用于在一个字符串中按固定宽度存储多个数字的场景，如"0000420000"中第4到6字节为"420"
+func (rc *redisClient) GetRangeInt(key string, start, end int64) (int64, error) {
+	value, err := rc.client().GetRange(rc.ctx, key, start, end).Result()
+	if err != nil {
+		return 0, fmt.Errorf("获取键 %s 的子串失败: %w", key, err)
+	}
+	if value == "" {
+		return 0, fmt.Errorf("键 %s 在 %d 到 %d 范围内没有数据", key, start, end)
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("键 %s 在 %d 到 %d 范围内的内容 %q 不是整数: %w", key, start, end, value, err)
+	}
+	log.Printf("获取子串整数成功: %s [%d, %d] -> %d", key, start, end, n)
+	return n, nil
+}
+
+// BitField 对字符串中的整数位段进行读写和自增，可将多个小计数器压缩存储在一个键中
+// args按BITFIELD的子命令格式依次传入，例如: "INCRBY", "u8", 0, 10, "GET", "u8", 0
+// 类型为i<位数>(有符号)或u<位数>(无符号)，偏移量为位偏移，"#2"表示第2个同类型位段；
+// 也支持"SET"以及"OVERFLOW WRAP|SAT|FAIL"，返回每个GET/SET/INCRBY子命令的结果
+func (rc *redisClient) BitField(key string, args ...interface{}) ([]int64, error) {
+	values, err := rc.client().BitField(rc.ctx, key, args...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("BITFIELD执行失败: %w", err)
+	}
+	log.Printf("BITFIELD执行成功: %s %v -> %v", key, args, values)
+	return values, nil
+}
+
+// ListRPush 从右侧推入列表元素
+func (rc *redisClient) ListRPush(key string, values ...interface{}) error {
+	err := rc.client().RPush(rc.ctx, key, values...).Err()
+	if err != nil {
+		return fmt.Errorf("推入列表元素失败: %w", err)
+	}
+	log.Printf("列表元素推入成功: %s -> %v", key, values)
+
+	return nil
+}
+
+// ListRPushCapped 从右侧推入列表元素，并裁剪列表只保留最右侧(最新)的max个元素，适用于固定长度的日志
+// RPUSH与LTRIM在同一个事务中执行，其他客户端不会看到超出长度的列表
+func (rc *redisClient) ListRPushCapped(key string, max int64, values ...interface{}) error {
+	if max <= 0 {
+		return fmt.Errorf("max 必须大于 0")
+	}
+	_, err := rc.client().TxPipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(rc.ctx, key, values...)
+		pipe.LTrim(rc.ctx, key, -max, -1)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("推入列表元素失败: %w", err)
+	}
+	log.Printf("列表元素推入成功: %s -> %v (最大长度: %d)", key, values, max)
+	return nil
+}
+
+// ListLPushCapped 从左侧推入列表元素，并裁剪列表只保留最左侧(最新)的max个元素
+// LPUSH与LTRIM在同一个事务中执行，其他客户端不会看到超出长度的列表
+func (rc *redisClient) ListLPushCapped(key string, max int64, values ...interface{}) error {
+	if max <= 0 {
+		return fmt.Errorf("max 必须大于 0")
+	}
+	_, err := rc.client().TxPipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(rc.ctx, key, values...)
+		pipe.LTrim(rc.ctx, key, 0, max-1)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("推入列表元素失败: %w", err)
+	}
+	log.Printf("列表元素推入成功: %s -> %v (最大长度: %d)", key, values, max)
+	return nil
+}
+
+// ListLLen 获取列表长度
+func (rc *redisClient) ListLLen(key string) (int64, error) {
+	length, err := rc.client().LLen(rc.ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("获取列表长度失败: %w", err)
+	}
+	log.Printf("列表长度: %d", length)
+	return length, nil
+}
+
+// ListLLenMany 在同一个pipeline中批量获取多个列表的长度，不存在的列表长度为0
+func (rc *redisClient) ListLLenMany(keys ...string) (map[string]int64, error) {
+	cmds := make([]*redis.IntCmd, len(keys))
+	_, err := rc.client().Pipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.LLen(rc.ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("批量获取列表长度失败: %w", err)
+	}
+
+	lengths := make(map[string]int64, len(keys))
+	for i, cmd := range cmds {
+		lengths[keys[i]] = cmd.Val()
+	}
+	log.Printf("列表长度: %v", lengths)
+	return lengths, nil
+}
+
+// ListLPop 从左侧弹出列表元素
+func (rc *redisClient) ListLPop(key string) (string, error) {
+	value, err := rc.client().LPop(rc.ctx, key).Result()
+	if err == redis.Nil {
+		return "", fmt.Errorf("列表 %s 为空", key)
+	} else if err != nil {
+		return "", fmt.Errorf("弹出列表元素失败: %w", err)
+	}
+	log.Printf("列表元素弹出成功: %s -> %s", key, value)
+	return value, nil
+}
+
+// ListLMPop 从多个列表中第一个非空的列表弹出最多count个元素(需Redis 7.0+)
+// direction为"LEFT"或"RIGHT"，返回弹出元素所在的列表及弹出的元素
+func (rc *redisClient) ListLMPop(direction string, count int64, keys ...string) (string, []string, error) {
+	key, values, err := rc.client().LMPop(rc.ctx, direction, count, keys...).Result()
+	if err == redis.Nil {
+		return "", nil, fmt.Errorf("列表 %v 均为空", keys)
+	} else if err != nil {
+		return "", nil, fmt.Errorf("弹出列表元素失败: %w", err)
+	}
+	log.Printf("列表元素弹出成功: %s -> %v", key, values)
+	return key, values, nil
+}
+
+// ListLRange 获取列表指定范围的元素[start, stop]
+func (rc *redisClient) ListLRange(key string, start, stop int64) ([]string, error) {
+	items, err := rc.client().LRange(rc.ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取列表元素失败: %w", err)
+	}
+	log.Printf("列表元素: %v", items)
+	return items, nil
+}
+
+// ListLRangeInt 获取列表指定范围的元素[start, stop]并解析为整数，遇到无法解析的元素时返回其下标
+func (rc *redisClient) ListLRangeInt(key string, start, stop int64) ([]int64, error) {
+	items, err := rc.ListLRange(key, start, stop)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]int64, len(items))
+	for i, item := range items {
+		n, err := strconv.ParseInt(item, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("列表 %s 下标 %d 的元素 %q 不是整数: %w", key, i, item, err)
+		}
+		values[i] = n
+	}
+	return values, nil
+}
+
+// SortList 对列表(或集合/有序集合)元素进行排序，不修改原数据
+// sort可设置By(按外部键权重排序)、Get(返回外部键的值)、Offset/Count(LIMIT)、Alpha(按字典序)和Order("ASC"/"DESC")
+func (rc *redisClient) SortList(key string, sort *redis.Sort) ([]string, error) {
+	items, err := rc.client().Sort(rc.ctx, key, sort).Result()
+	if err != nil {
+		return nil, fmt.Errorf("排序失败: %w", err)
+	}
+	log.Printf("排序结果: %s -> %v", key, items)
+	return items, nil
+}
+
+// SetSAdd 添加元素到集合
+func (rc *redisClient) SetSAdd(key string, members ...interface{}) error {
+	err := rc.client().SAdd(rc.ctx, key, members...).Err()
+	if err != nil {
+		return fmt.Errorf("添加集合元素失败: %w", err)
+	}
+	log.Printf("集合元素添加成功: %s -> %v", key, members)
+	return nil
+}
+
+// SetSAddCard 添加集合元素并返回添加后集合的元素数量
+// SADD与SCARD在同一个事务中执行，返回的数量不会包含其他客户端在两者之间的修改
+func (rc *redisClient) SetSAddCard(key string, members ...interface{}) (int64, error) {
+	var cardCmd *redis.IntCmd
+	_, err := rc.client().TxPipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(rc.ctx, key, members...)
+		cardCmd = pipe.SCard(rc.ctx, key)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("添加集合元素失败: %w", err)
+	}
+	cardinality := cardCmd.Val()
+	log.Printf("集合元素添加成功: %s -> %v (元素数量: %d)", key, members, cardinality)
+	return cardinality, nil
+}
+
+// SetSRem 移除集合中的元素
+func (rc *redisClient) SetSRem(key string, members ...interface{}) error {
+	err := rc.client().SRem(rc.ctx, key, members...).Err()
+	if err != nil {
+		return fmt.Errorf("移除集合元素失败: %w", err)
+	}
+	log.Printf("集合元素移除成功: %s -> %v", key, members)
+	return nil
+}
+
+// SetSRemReport 移除集合中的元素，返回实际存在并被移除的元素数量
+func (rc *redisClient) SetSRemReport(key string, members ...interface{}) (int64, error) {
+	removed, err := rc.client().SRem(rc.ctx, key, members...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("移除集合元素失败: %w", err)
+	}
+	log.Printf("集合元素移除成功: %s -> %v (实际移除: %d)", key, members, removed)
+	return removed, nil
+}
+
+// SetSMembers 获取集合所有元素
+func (rc *redisClient) SetSMembers(key string) ([]string, error) {
+	members, err := rc.client().SMembers(rc.ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取集合元素失败: %w", err)
+	}
+	log.Printf("集合所有元素: %v", members)
+	return members, nil
+}
+
+// SetSMembersInt 获取集合所有元素并解析为整数，按升序返回，遇到无法解析的元素时返回错误
+func (rc *redisClient) SetSMembersInt(key string) ([]int64, error) {
+	members, err := rc.SetSMembers(key)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]int64, len(members))
+	for i, member := range members {
+		n, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("集合 %s 的元素 %q 不是整数: %w", key, member, err)
+		}
+		values[i] = n
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values, nil
+}
+
+// SetSMembersSet 获取集合所有元素并以map[string]struct{}返回，适用于在客户端多次判断元素是否存在
+func (rc *redisClient) SetSMembersSet(key string) (map[string]struct{}, error) {
+	members, err := rc.client().SMembersMap(rc.ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取集合元素失败: %w", err)
+	}
+	log.Printf("集合 %s 共 %d 个元素", key, len(members))
+	return members, nil
+}
+
+// SetSIsMember 检查元素是否在集合中
+func (rc *redisClient) SetSIsMember(key string, member interface{}) (bool, error) {
+	isMember, err := rc.client().SIsMember(rc.ctx, key, member).Result()
+	if err != nil {
+		return false, fmt.Errorf("检查集合元素失败: %w", err)
+	}
+	log.Printf("元素 %v 是否在集合 %s 中: %t", member, key, isMember)
+	return isMember, nil
+}
+
+// SetSCard 获取集合元素数量
+func (rc *redisClient) SetSCard(key string) (int64, error) {
+	cardinality, err := rc.client().SCard(rc.ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("获取集合元素数量失败: %w", err)
+	}
+	log.Printf("集合元素数量: %d", cardinality)
+	return cardinality, nil
+}
+
+// SetSInterCard 获取多个集合交集的元素数量(SINTERCARD，需要Redis 7.0+)，不在客户端生成交集
+// limit大于0时计数达到limit即停止并返回limit，为0表示不限制
+func (rc *redisClient) SetSInterCard(limit int64, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, fmt.Errorf("keys 不能为空")
+	}
+	if limit < 0 {
+		return 0, fmt.Errorf("limit 不能为负数")
+	}
+	cardinality, err := rc.client().SInterCard(rc.ctx, limit, keys...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("获取集合交集元素数量失败: %w", err)
+	}
+	log.Printf("集合 %v 交集元素数量: %d", keys, cardinality)
+	return cardinality, nil
+}
+
+// SetSCardMany 在同一个pipeline中批量获取多个集合的元素数量，不存在的集合数量为0
+func (rc *redisClient) SetSCardMany(keys ...string) (map[string]int64, error) {
+	cmds := make([]*redis.IntCmd, len(keys))
+	_, err := rc.client().Pipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.SCard(rc.ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("批量获取集合元素数量失败: %w", err)
+	}
+
+	cardinalities := make(map[string]int64, len(keys))
+	for i, cmd := range cmds {
+		cardinalities[keys[i]] = cmd.Val()
+	}
+	log.Printf("集合元素数量: %v", cardinalities)
+	return cardinalities, nil
+}
+
+// SetSRandMember 随机获取集合中的一个元素
+func (rc *redisClient) SetSRandMember(key string) (string, error) {
+	randomMember, err := rc.client().SRandMember(rc.ctx, key).Result()
+	if err != nil {
+		return "", fmt.Errorf("随机获取集合元素失败: %w", err)
+	}
+	log.Printf("随机获取的元素: %s", randomMember)
+	return randomMember, nil
+}
+
+// SetSRandMemberN 随机获取集合中的多个元素，count原样传给Redis:
+// count为正数时返回最多count个不重复的元素，为负数时返回恰好|count|个元素且可能重复
+func (rc *redisClient) SetSRandMemberN(key string, count int64) ([]string, error) {
+	members, err := rc.client().SRandMemberN(rc.ctx, key, count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("随机获取集合元素失败: %w", err)
+	}
+	log.Printf("随机获取的元素: %v", members)
+	return members, nil
+}
+
+// SetSNewSince 计算两个快照之间新增的元素: 将SDIFF currKey prevKey的结果存储到destKey，返回新增元素数量
+// destKey已存在时会被覆盖，没有新增元素时destKey会被删除
+func (rc *redisClient) SetSNewSince(prevKey, currKey, destKey string) (int64, error) {
+	count, err := rc.client().SDiffStore(rc.ctx, destKey, currKey, prevKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("计算集合新增元素失败: %w", err)
+	}
+	log.Printf("集合 %s 相对 %s 新增元素 %d 个，已存储到 %s", currKey, prevKey, count, destKey)
+	return count, nil
+}
+
+// SetZAdd 添加/更新有序集合中的元素（带分数）
+func (rc *redisClient) SetZAdd(key string, members ...redis.Z) error {
+	err := rc.client().ZAdd(rc.ctx, key, members...).Err()
+	if err != nil {
+		return fmt.Errorf("添加/更新有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合元素添加/更新成功: %s -> %v", key, members)
+	return nil
+}
+
+// SetZAddGT 添加有序集合元素，已存在的元素仅当新分数大于当前分数时才更新(需Redis 6.2+)
+// 返回新增或分数发生变化的元素数量(ZADD GT CH)
+func (rc *redisClient) SetZAddGT(key string, members ...redis.Z) (int64, error) {
+	changed, err := rc.client().ZAddArgs(rc.ctx, key, redis.ZAddArgs{
+		GT:      true,
+		Ch:      true,
+		Members: members,
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("添加/更新有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合元素添加/更新(仅更高分数): %s -> %v (变化: %d)", key, members, changed)
+	return changed, nil
+}
+
+// SetZAddLT 添加有序集合元素，已存在的元素仅当新分数小于当前分数时才更新(需Redis 6.2+)
+// 返回新增或分数发生变化的元素数量(ZADD LT CH)
+func (rc *redisClient) SetZAddLT(key string, members ...redis.Z) (int64, error) {
+	changed, err := rc.client().ZAddArgs(rc.ctx, key, redis.ZAddArgs{
+		LT:      true,
+		Ch:      true,
+		Members: members,
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("添加/更新有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合元素添加/更新(仅更低分数): %s -> %v (变化: %d)", key, members, changed)
+	return changed, nil
+}
+
+// SetZUpdateScore 仅当member已存在时将其分数更新为score(ZADD XX)，返回member是否存在并已更新
+// ZADD XX CH在新旧分数相同时返回0，因此在同一个事务中先用ZSCORE判断元素是否存在
+func (rc *redisClient) SetZUpdateScore(key, member string, score float64) (bool, error) {
+	var scoreCmd *redis.FloatCmd
+	_, err := rc.client().TxPipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		scoreCmd = pipe.ZScore(rc.ctx, key, member)
+		pipe.ZAddXX(rc.ctx, key, redis.Z{Score: score, Member: member})
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return false, fmt.Errorf("更新有序集合元素分数失败: %w", err)
+	}
+	updated := scoreCmd.Err() == nil
+	log.Printf("更新有序集合元素分数: %s %s -> %v (是否更新: %t)", key, member, score, updated)
+	return updated, nil
+}
+
+// SetZRem 移除有序集合中的元素
+func (rc *redisClient) SetZRem(key string, members ...interface{}) error {
+	err := rc.client().ZRem(rc.ctx, key, members...).Err()
+	if err != nil {
+		return fmt.Errorf("移除有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合元素移除成功: %s -> %v", key, members)
+	return nil
+}
+
+// SetZRemRangeByRank 移除有序集合中指定排名范围(按分数升序)的元素 [start, stop]，返回移除的数量
+// 排名从0开始，负数表示从分数最高的一端倒数，例如(0, -1)移除全部元素，
+// (0, -2)移除除分数最高之外的全部元素，(-1, -1)仅移除分数最高的元素；排名原样传给Redis
+func (rc *redisClient) SetZRemRangeByRank(key string, start, stop int64) (int64, error) {
+	removed, err := rc.client().ZRemRangeByRank(rc.ctx, key, start, stop).Result()
+	if err != nil {
+		return 0, fmt.Errorf("按排名移除有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合 %s 按排名 %d 到 %d 移除元素: %d 个", key, start, stop, removed)
+	return removed, nil
+}
+
+// SetZRange 获取有序集合指定范围的元素(按分数升序) [start, stop]
+func (rc *redisClient) SetZRange(key string, start, stop int64) ([]string, error) {
+	members, err := rc.client().ZRange(rc.ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合所有元素（按分数升序）: %v", members)
+	return members, nil
+}
+
+// SetZRevRange 获取有序集合指定范围的元素(按分数降序) [start, stop]
+func (rc *redisClient) SetZRevRange(key string, start, stop int64) ([]string, error) {
+	members, err := rc.client().ZRevRange(rc.ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合所有元素（按分数降序）: %v", members)
+	return members, nil
+}
+
+// SetZRevRangeWithScores 获取有序集合指定范围的元素及分数(按分数降序) [start, stop]
+func (rc *redisClient) SetZRevRangeWithScores(key string, start, stop int64) ([]redis.Z, error) {
+	members, err := rc.client().ZRevRangeWithScores(rc.ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合元素及分数（按分数降序）: %v", members)
+	return members, nil
+}
+
+// SetZTopN 获取有序集合中分数最高的n个元素及分数(按分数降序)，常用于排行榜
+func (rc *redisClient) SetZTopN(key string, n int64) ([]redis.Z, error) {
+	if n <= 0 {
+		return []redis.Z{}, nil
+	}
+	return rc.SetZRevRangeWithScores(key, 0, n-1)
+}
+
+// SetZBottomN 获取有序集合中分数最低的n个元素及分数(按分数升序)
+func (rc *redisClient) SetZBottomN(key string, n int64) ([]redis.Z, error) {
+	if n <= 0 {
+		return []redis.Z{}, nil
+	}
+	members, err := rc.client().ZRangeWithScores(rc.ctx, key, 0, n-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合 %s 分数最低的 %d 个元素: %v", key, n, members)
+	return members, nil
+}
+
+// SetZCard 获取有序集合元素数量
+func (rc *redisClient) SetZCard(key string) (int64, error) {
+	cardinality, err := rc.client().ZCard(rc.ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("获取有序集合元素数量失败: %w", err)
+	}
+	log.Printf("有序集合元素数量: %d", cardinality)
+	return cardinality, nil
+}
+
+// SetZCardMany 在同一个pipeline中批量获取多个有序集合的元素数量，不存在的有序集合数量为0
+func (rc *redisClient) SetZCardMany(keys ...string) (map[string]int64, error) {
+	cmds := make([]*redis.IntCmd, len(keys))
+	_, err := rc.client().Pipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.ZCard(rc.ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("批量获取有序集合元素数量失败: %w", err)
+	}
+
+	cardinalities := make(map[string]int64, len(keys))
+	for i, cmd := range cmds {
+		cardinalities[keys[i]] = cmd.Val()
+	}
+	log.Printf("有序集合元素数量: %v", cardinalities)
+	return cardinalities, nil
+}
+
+// SetZCountRange 统计有序集合中分数在[min, max]范围内的元素数量，可使用math.Inf表示无边界
+func (rc *redisClient) SetZCountRange(key string, min, max float64) (int64, error) {
+	count, err := rc.client().ZCount(rc.ctx, key, formatScoreBound(min), formatScoreBound(max)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("统计有序集合元素数量失败: %w", err)
+	}
+	log.Printf("有序集合 %s 分数在 %v 到 %v 范围内的元素数量: %d", key, min, max, count)
+	return count, nil
+}
+
+// SetZLexCount 统计有序集合中字典序在[min, max]范围内的元素数量，要求所有元素分数相同
+// 边界格式为"[a"(包含)、"(a"(不包含)、"+"、"-"
+func (rc *redisClient) SetZLexCount(key, min, max string) (int64, error) {
+	count, err := rc.client().ZLexCount(rc.ctx, key, min, max).Result()
+	if err != nil {
+		return 0, fmt.Errorf("统计有序集合元素数量失败: %w", err)
+	}
+	log.Printf("有序集合 %s 字典序在 %s 到 %s 范围内的元素数量: %d", key, min, max, count)
+	return count, nil
+}
+
+// formatScoreBound 将分数格式化为Redis的分数边界，无穷大格式化为"+inf"/"-inf"
+func formatScoreBound(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "+inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	default:
+		return strconv.FormatFloat(score, 'f', -1, 64)
+	}
+}
+
+// parseScoreBound 解析分数边界，支持"(1.5"(不包含)、"-inf"、"+inf"等Redis格式
+func parseScoreBound(bound string) (float64, error) {
+	score, err := strconv.ParseFloat(strings.TrimPrefix(bound, "("), 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的分数边界: %s", bound)
+	}
+	return score, nil
+}
+
+// validateScoreRange 按数值比较分数边界，要求min小于等于max
+func validateScoreRange(min, max string) error {
+	minScore, err := parseScoreBound(min)
+	if err != nil {
+		return err
+	}
+	maxScore, err := parseScoreBound(max)
+	if err != nil {
+		return err
+	}
+	if minScore > maxScore {
+		return fmt.Errorf("min 必须小于等于 max")
+	}
+	return nil
+}
+
+// SetZRangeByScore 获取有序集合指定分数范围内的元素(按分数升序) [min, max] [start, stop]
+// start/stop为分数范围内结果的下标，stop为负数表示到最后一个元素，内部转换为LIMIT后调用SetZRangeByScoreLimit
+func (rc *redisClient) SetZRangeByScore(key string, min, max string, start, stop int64) ([]string, error) {
+	offset, count := rangeToLimit(start, stop)
+	return rc.SetZRangeByScoreLimit(key, min, max, offset, count)
+}
+
+// SetZRangeByScoreLimit 获取有序集合指定分数范围内的元素(按分数升序) [min, max]
+// offset/count直接对应Redis的LIMIT: 跳过前offset个元素后最多返回count个，count小于0表示不限数量
+func (rc *redisClient) SetZRangeByScoreLimit(key, min, max string, offset, count int64) ([]string, error) {
+	if err := validateScoreRange(min, max); err != nil {
+		return nil, err
+	}
+	count, ok := normalizeLimitCount(count)
+	if !ok {
+		return []string{}, nil
+	}
+
+	members, err := rc.client().ZRangeByScore(rc.ctx, key, &redis.ZRangeBy{
+		Min:    min,
+		Max:    max,
+		Offset: offset,
+		Count:  count,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合，在 %s 到 %s 分数，跳过 %d 个后最多 %d 个元素（按分数升序）: %v", min, max, offset, count, members)
+	return members, nil
+}
+
+// rangeToLimit 将下标范围[start, stop]转换为LIMIT的offset/count，stop为负数时count为-1(不限数量)
+func rangeToLimit(start, stop int64) (int64, int64) {
+	if stop < 0 {
+		return start, -1
+	}
+	count := stop - start + 1
+	if count < 0 {
+		count = 0
+	}
+	return start, count
+}
+
+// normalizeLimitCount 规范化LIMIT的count，count小于0统一为-1(不限数量)
+// 返回false表示count为0，结果必然为空无需请求Redis(go-redis会发送"LIMIT offset 0")
+func normalizeLimitCount(count int64) (int64, bool) {
+	if count == 0 {
+		return 0, false
+	}
+	if count < 0 {
+		return -1, true
+	}
+	return count, true
+}
+
+// SetZRevRangeByScore 获取有序集合指定分数范围内的元素(按分数降序) [min, max] [start, stop]
+// start/stop为分数范围内结果的下标，stop为负数表示到最后一个元素，内部转换为LIMIT后调用SetZRevRangeByScoreLimit
+func (rc *redisClient) SetZRevRangeByScore(key string, min, max string, start, stop int64) ([]string, error) {
+	offset, count := rangeToLimit(start, stop)
+	return rc.SetZRevRangeByScoreLimit(key, max, min, offset, count)
+}
+
+// SetZRevRangeByScoreLimit 获取有序集合指定分数范围内的元素(按分数降序) [min, max]
+// 注意参数顺序为先max后min，与ZREVRANGEBYSCORE一致；offset/count直接对应LIMIT，count小于0表示不限数量
+func (rc *redisClient) SetZRevRangeByScoreLimit(key, max, min string, offset, count int64) ([]string, error) {
+	if err := validateScoreRange(min, max); err != nil {
+		return nil, err
+	}
+	count, ok := normalizeLimitCount(count)
+	if !ok {
+		return []string{}, nil
+	}
+
+	members, err := rc.client().ZRevRangeByScore(rc.ctx, key, &redis.ZRangeBy{
+		Min:    min,
+		Max:    max,
+		Offset: offset,
+		Count:  count,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合，在 %s 到 %s 分数，跳过 %d 个后最多 %d 个元素（按分数降序）: %v", max, min, offset, count, members)
+	return members, nil
+}
+
+// SetZRevRangeByLex 获取有序集合指定字典序范围内的元素(按字典序降序)，要求所有元素分数相同
+// 注意参数顺序为先max后min，与ZRANGEBYLEX相反；边界格式为"[a"(包含)、"(a"(不包含)、"+"、"-"
+func (rc *redisClient) SetZRevRangeByLex(key, max, min string) ([]string, error) {
+	members, err := rc.client().ZRevRangeByLex(rc.ctx, key, &redis.ZRangeBy{
+		Min: min,
+		Max: max,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合，在 %s 到 %s 字典序范围内的所有元素（按字典序降序）: %v", max, min, members)
+	return members, nil
+}
+
+// SetZRangeByScoreWithScores 获取有序集合指定分数范围内的元素及分数(按分数升序) [min, max]
+// offset/count与SetZRangeByScoreLimit相同，count小于0表示返回offset之后的全部元素
+func (rc *redisClient) SetZRangeByScoreWithScores(key, min, max string, offset, count int64) ([]redis.Z, error) {
+	if err := validateScoreRange(min, max); err != nil {
+		return nil, err
+	}
+	count, ok := normalizeLimitCount(count)
+	if !ok {
+		return []redis.Z{}, nil
+	}
+
+	members, err := rc.client().ZRangeByScoreWithScores(rc.ctx, key, &redis.ZRangeBy{
+		Min:    min,
+		Max:    max,
+		Offset: offset,
+		Count:  count,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合，在 %s 到 %s 分数范围内的元素及分数（按分数升序）: %v", min, max, members)
+	return members, nil
+}
+
+// SetZRevRangeByScoreWithScores 获取有序集合指定分数范围内的元素及分数(按分数降序) [min, max]
+// 参数顺序与SetZRevRangeByScoreLimit一样为先max后min；offset/count与其相同，count小于0表示返回offset之后的全部元素
+func (rc *redisClient) SetZRevRangeByScoreWithScores(key, max, min string, offset, count int64) ([]redis.Z, error) {
+	if err := validateScoreRange(min, max); err != nil {
+		return nil, err
+	}
+	count, ok := normalizeLimitCount(count)
+	if !ok {
+		return []redis.Z{}, nil
+	}
+
+	members, err := rc.client().ZRevRangeByScoreWithScores(rc.ctx, key, &redis.ZRangeBy{
+		Min:    min,
+		Max:    max,
+		Offset: offset,
+		Count:  count,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合，在 %s 到 %s 分数范围内的元素及分数（按分数降序）: %v", max, min, members)
+	return members, nil
+}
+
+// SetZRangeByScoreMap 获取有序集合分数在[min, max]范围内的所有元素及分数，返回元素到分数的map
+// map不保留顺序，仅适用于按元素查找分数；需要按分数顺序展示时使用SetZRangeByScoreWithScores
+func (rc *redisClient) SetZRangeByScoreMap(key, min, max string) (map[string]float64, error) {
+	members, err := rc.SetZRangeByScoreWithScores(key, min, max, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[string]float64, len(members))
+	for _, z := range members {
+		scores[fmt.Sprint(z.Member)] = z.Score
+	}
+	return scores, nil
+}
+
+// SetZScore 获取有序集合中元素的分数
+func (rc *redisClient) SetZScore(key string, member string) error {
+	score, err := rc.client().ZScore(rc.ctx, key, member).Result()
+	if err != nil {
+		return fmt.Errorf("获取元素分数失败: %w", err)
+	}
+	log.Printf("元素 %s 的分数为 %f", member, score)
+	return nil
+}
+
+// SetZScoreMap 使用ZMSCORE批量获取有序集合中元素的分数(需Redis 6.2+)，不存在的元素不会出现在结果中
+// go-redis的ZMScore会将不存在的元素返回为0，无法与分数为0的元素区分，因此这里直接解析原始回复
+func (rc *redisClient) SetZScoreMap(key string, members ...string) (map[string]float64, error) {
+	args := make([]interface{}, 0, len(members)+2)
+	args = append(args, "ZMSCORE", key)
+	for _, member := range members {
+		args = append(args, member)
+	}
+
+	replies, err := rc.client().Do(rc.ctx, args...).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("批量获取元素分数失败: %w", err)
+	}
+
+	scores := make(map[string]float64, len(replies))
+	for i, reply := range replies {
+		switch v := reply.(type) {
+		case nil:
+			// 元素不存在
+		case float64:
+			scores[members[i]] = v
+		case string:
+			score, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("元素 %s 的分数 %q 无法解析: %w", members[i], v, err)
+			}
+			scores[members[i]] = score
+		default:
+			return nil, fmt.Errorf("元素 %s 的分数类型 %T 无法解析", members[i], reply)
+		}
+	}
+	log.Printf("有序集合 %s 元素分数: %v", key, scores)
+	return scores, nil
+}
+
+// SetZIncrBy 增加有序集合中元素的分数，返回增加后的分数
+func (rc *redisClient) SetZIncrBy(key string, member string, increment float64) (float64, error) {
+	newScore, err := rc.client().ZIncrBy(rc.ctx, key, increment, member).Result()
+	if err != nil {
+		return 0, fmt.Errorf("增加元素分数失败: %w", err)
+	}
+	log.Printf("元素 %s 的分数增加为 %f", member, newScore)
+	return newScore, nil
+}
+
+// SetZRank 获取有序集合中元素的排名（按分数升序）
+func (rc *redisClient) SetZRank(key string, member string) error {
+	rank, err := rc.client().ZRank(rc.ctx, key, member).Result()
+	if err != nil {
+		return fmt.Errorf("获取元素排名失败: %w", err)
+	}
+	log.Printf("元素 %s 的排名为 %d(按分数升序)", member, rank)
+	return nil
+}
+
+// SetZRevRank 获取有序集合中元素的排名（按分数降序）
+func (rc *redisClient) SetZRevRank(key string, member string) error {
+	rank, err := rc.client().ZRevRank(rc.ctx, key, member).Result()
+	if err != nil {
+		return fmt.Errorf("获取元素排名失败: %w", err)
+	}
+	log.Printf("元素 %s 的排名为 %d(按分数降序)", member, rank)
+	return nil
+}
+
+// SetZRankWithScore 同时获取有序集合中元素的排名（按分数升序）和分数(需Redis 7.2+)
+func (rc *redisClient) SetZRankWithScore(key, member string) (int64, float64, error) {
+	result, err := rc.client().ZRankWithScore(rc.ctx, key, member).Result()
+	if err == redis.Nil {
+		return 0, 0, fmt.Errorf("有序集合 %s 中不存在元素: %s", key, member)
+	} else if err != nil {
+		return 0, 0, fmt.Errorf("获取元素排名和分数失败: %w", err)
+	}
+	log.Printf("元素 %s 的排名为 %d(按分数升序)，分数为 %f", member, result.Rank, result.Score)
+	return result.Rank, result.Score, nil
+}
+
+// SetZPopMinCount 弹出有序集合中分数最低的count个元素，保证按分数升序返回
+func (rc *redisClient) SetZPopMinCount(key string, count int64) ([]redis.Z, error) {
+	members, err := rc.client().ZPopMin(rc.ctx, key, count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("弹出有序集合元素失败: %w", err)
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].Score < members[j].Score
+	})
+	log.Printf("有序集合 %s 弹出分数最低的元素: %v", key, members)
+	return members, nil
+}
+
+// SetZPopMaxCount 弹出有序集合中分数最高的count个元素，保证按分数降序返回
+func (rc *redisClient) SetZPopMaxCount(key string, count int64) ([]redis.Z, error) {
+	members, err := rc.client().ZPopMax(rc.ctx, key, count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("弹出有序集合元素失败: %w", err)
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].Score > members[j].Score
+	})
+	log.Printf("有序集合 %s 弹出分数最高的元素: %v", key, members)
+	return members, nil
+}
+
+// SetZMPop 从多个有序集合中第一个非空的集合弹出最多count个元素(需Redis 7.0+)
+// min为true时弹出分数最低的元素(按分数升序返回)，否则弹出分数最高的元素(按分数降序返回)
+func (rc *redisClient) SetZMPop(min bool, count int64, keys ...string) (string, []redis.Z, error) {
+	order := "max"
+	if min {
+		order = "min"
+	}
+	key, members, err := rc.client().ZMPop(rc.ctx, order, count, keys...).Result()
+	if err == redis.Nil {
+		return "", nil, fmt.Errorf("有序集合 %v 均为空", keys)
+	} else if err != nil {
+		return "", nil, fmt.Errorf("弹出有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合 %s 弹出元素: %v", key, members)
+	return key, members, nil
+}
+
+// SetBZMPop 从多个有序集合中第一个非空的集合弹出最多count个元素(需Redis 7.0+)，所有集合都为空时阻塞等待
+// min含义与SetZMPop相同；timeout为0表示一直阻塞，超时后返回包装了ErrTimeout的错误
+func (rc *redisClient) SetBZMPop(timeout time.Duration, min bool, count int64, keys ...string) (string, []redis.Z, error) {
+	order := "max"
+	if min {
+		order = "min"
+	}
+	key, members, err := rc.client().BZMPop(rc.ctx, timeout, order, count, keys...).Result()
+	if err == redis.Nil {
+		return "", nil, fmt.Errorf("%w: 有序集合 %v 在 %v 内均为空", ErrTimeout, keys, timeout)
+	} else if err != nil {
+		return "", nil, fmt.Errorf("弹出有序集合元素失败: %w", err)
+	}
+	log.Printf("有序集合 %s 弹出元素: %v", key, members)
+	return key, members, nil
+}
+
+// SetZUnion 获取多个有序集合的并集(不存储结果，需Redis 6.2+)
+func (rc *redisClient) SetZUnion(store *redis.ZStore) ([]string, error) {
+	members, err := rc.client().ZUnion(rc.ctx, *store).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取有序集合并集失败: %w", err)
+	}
+	log.Printf("有序集合 %v 的并集: %v", store.Keys, members)
+	return members, nil
+}
+
+// SetZUnionWithScores 获取多个有序集合的并集及分数(不存储结果，需Redis 6.2+)
+func (rc *redisClient) SetZUnionWithScores(store *redis.ZStore) ([]redis.Z, error) {
+	members, err := rc.client().ZUnionWithScores(rc.ctx, *store).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取有序集合并集失败: %w", err)
+	}
+	log.Printf("有序集合 %v 的并集(带分数): %v", store.Keys, members)
+	return members, nil
+}
+
+// SetZInter 获取多个有序集合的交集(不存储结果，需Redis 6.2+)
+func (rc *redisClient) SetZInter(store *redis.ZStore) ([]string, error) {
+	members, err := rc.client().ZInter(rc.ctx, store).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取有序集合交集失败: %w", err)
+	}
+	log.Printf("有序集合 %v 的交集: %v", store.Keys, members)
+	return members, nil
+}
+
+// SetZInterWithScores 获取多个有序集合的交集及分数(不存储结果，需Redis 6.2+)
+func (rc *redisClient) SetZInterWithScores(store *redis.ZStore) ([]redis.Z, error) {
+	members, err := rc.client().ZInterWithScores(rc.ctx, store).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取有序集合交集失败: %w", err)
+	}
+	log.Printf("有序集合 %v 的交集(带分数): %v", store.Keys, members)
+	return members, nil
+}
+
+// SetZDiffWithScores 获取第一个有序集合与其他集合的差集及分数(需Redis 6.2+)，分数取自第一个集合
+func (rc *redisClient) SetZDiffWithScores(keys ...string) ([]redis.Z, error) {
+	members, err := rc.client().ZDiffWithScores(rc.ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取有序集合差集失败: %w", err)
+	}
+	log.Printf("有序集合 %v 的差集(带分数): %v", keys, members)
+	return members, nil
+}
+
+// SetZScanPairs 使用ZSCAN增量遍历有序集合，将返回的元素/分数交替列表转换为redis.Z
+// 返回本次遍历的元素及下一次遍历的游标，游标为0表示遍历结束
+func (rc *redisClient) SetZScanPairs(key string, cursor uint64, match string, count int64) ([]redis.Z, uint64, error) {
+	items, next, err := rc.client().ZScan(rc.ctx, key, cursor, match, count).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("遍历有序集合失败: %w", err)
+	}
+	if len(items)%2 != 0 {
+		return nil, 0, fmt.Errorf("遍历有序集合返回的元素数量异常: %d", len(items))
+	}
+
+	members := make([]redis.Z, 0, len(items)/2)
+	for i := 0; i < len(items); i += 2 {
+		score, err := strconv.ParseFloat(items[i+1], 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("元素 %s 的分数 %q 无法解析: %w", items[i], items[i+1], err)
+		}
+		members = append(members, redis.Z{Score: score, Member: items[i]})
+	}
+	log.Printf("遍历有序集合 %s: %d 个元素, 下一个游标: %d", key, len(members), next)
+	return members, next, nil
+}
+
+// SetHashSet 设置哈希字段
+func (rc *redisClient) HashSet(hashKey string, values ...interface{}) error {
+	err := rc.client().HSet(rc.ctx, hashKey, values...).Err()
+	if err != nil {
+		return fmt.Errorf("设置哈希字段失败: %w", err)
+	}
+	log.Printf("哈希字段 %s 设置成功: %v", hashKey, values)
+	return nil
+}
+
+// HashSetWithTTL 设置多个哈希字段并为整个哈希设置过期时间
+// HSET与EXPIRE在同一个事务中执行，不会出现哈希已写入但没有过期时间的情况
+func (rc *redisClient) HashSetWithTTL(hashKey string, fields map[string]string, ttl time.Duration) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("fields 不能为空")
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("ttl 必须大于 0")
+	}
+	_, err := rc.client().TxPipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(rc.ctx, hashKey, fields)
+		pipe.Expire(rc.ctx, hashKey, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("设置哈希字段失败: %w", err)
+	}
+	log.Printf("哈希字段 %s 设置成功: %v (过期时间: %v)", hashKey, fields, ttl)
+	return nil
+}
+
+// SetHashGetAll 获取哈希字段的所有值
+func (rc *redisClient) HashGetAll(hashKey string) (map[string]string, error) {
+	fields, err := rc.client().HGetAll(rc.ctx, hashKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取哈希字段失败: %w", err)
+	}
+	log.Printf("哈希字段: %v", fields)
+	return fields, nil
+}
+
+// HashGetAllBatch 在同一个pipeline中批量获取多个哈希的所有字段，不存在或为空的哈希不会出现在结果中
+func (rc *redisClient) HashGetAllBatch(hashKeys ...string) (map[string]map[string]string, error) {
+	cmds := make([]*redis.MapStringStringCmd, len(hashKeys))
+	_, err := rc.client().Pipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		for i, hashKey := range hashKeys {
+			cmds[i] = pipe.HGetAll(rc.ctx, hashKey)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("批量获取哈希字段失败: %w", err)
+	}
+
+	result := make(map[string]map[string]string, len(hashKeys))
+	for i, cmd := range cmds {
+		if fields := cmd.Val(); len(fields) > 0 {
+			result[hashKeys[i]] = fields
+		}
+	}
+	log.Printf("批量获取哈希字段: %v", result)
+	return result, nil
+}
+
+// SetHashGet 获取哈希字段的值
+func (rc *redisClient) HashGet(hashKey string, field string) (string, error) {
+	value, err := rc.client().HGet(rc.ctx, hashKey, field).Result()
+	if err != nil {
+		return "", fmt.Errorf("获取哈希字段失败: %w", err)
+	}
+	log.Printf("哈希字段 %s 的值为 %s", field, value)
+	return value, nil
+}
+
+// HashGetInt 获取哈希字段的值并解析为整数
+func (rc *redisClient) HashGetInt(hashKey, field string) (int64, error) {
+	value, err := rc.HashGet(hashKey, field)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("哈希 %s 字段 %s 的值 %q 不是整数: %w", hashKey, field, value, err)
+	}
+	return n, nil
+}
+
+// HashGetFloat 获取哈希字段的值并解析为浮点数
+func (rc *redisClient) HashGetFloat(hashKey, field string) (float64, error) {
+	value, err := rc.HashGet(hashKey, field)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("哈希 %s 字段 %s 的值 %q 不是浮点数: %w", hashKey, field, value, err)
+	}
+	return f, nil
+}
+
+// HashGetBool 获取哈希字段的值并解析为布尔值，支持"1"/"0"、"true"/"false"等格式
+func (rc *redisClient) HashGetBool(hashKey, field string) (bool, error) {
+	value, err := rc.HashGet(hashKey, field)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("哈希 %s 字段 %s 的值 %q 不是布尔值: %w", hashKey, field, value, err)
+	}
+	return b, nil
+}
+
+// hashIncrByCappedScript 仅当增加后的值不超过上限时执行HINCRBY，返回{当前值, 是否被限制}
+var hashIncrByCappedScript = redis.NewScript(`
+local current = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0')
+if current + tonumber(ARGV[2]) > tonumber(ARGV[3]) then
+	return {current, 1}
+end
+return {redis.call('HINCRBY', KEYS[1], ARGV[1], ARGV[2]), 0}
+`)
+
+// HashIncrByCapped 将哈希字段的值增加delta，若结果超过max则不修改并返回capped=true，适用于配额计数
+// 返回操作后字段的值，字段不存在时视为0
+func (rc *redisClient) HashIncrByCapped(hashKey, field string, delta, max int64) (int64, bool, error) {
+	result, err := hashIncrByCappedScript.Run(rc.ctx, rc.client(), []string{hashKey}, field, delta, max).Int64Slice()
+	if err != nil {
+		return 0, false, fmt.Errorf("增加哈希字段值失败: %w", err)
+	}
+	if len(result) != 2 {
+		return 0, false, fmt.Errorf("增加哈希字段值返回结果异常: %v", result)
+	}
+	capped := result[1] == 1
+	log.Printf("哈希 %s 字段 %s 增加 %d: %d (上限: %d, 是否被限制: %t)", hashKey, field, delta, result[0], max, capped)
+	return result[0], capped, nil
+}
+
+// hashCompareAndSetScript 版本字段等于预期值时写入所有字段并将版本号加1，返回是否写入
+var hashCompareAndSetScript = redis.NewScript(`
+local current = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0')
+if current ~= tonumber(ARGV[2]) then
+	return 0
+end
+if #ARGV > 2 then
+	redis.call('HSET', KEYS[1], unpack(ARGV, 3))
+end
+redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+return 1
+`)
+
+// HashCompareAndSet 乐观锁更新: 仅当versionField的值等于expectedVersion时写入updates并将版本号加1，返回是否写入
+// 哈希或版本字段不存在时视为版本0；版本不匹配时哈希保持不变
+func (rc *redisClient) HashCompareAndSet(hashKey, versionField string, expectedVersion int64, updates map[string]string) (bool, error) {
+	args := make([]interface{}, 0, len(updates)*2+2)
+	args = append(args, versionField, expectedVersion)
+	for field, value := range updates {
+		args = append(args, field, value)
+	}
+
+	applied, err := hashCompareAndSetScript.Run(rc.ctx, rc.client(), []string{hashKey}, args...).Int()
+	if err != nil {
+		return false, fmt.Errorf("按版本更新哈希字段失败: %w", err)
+	}
+	log.Printf("按版本更新哈希 %s: %v (预期版本: %d, 是否写入: %t)", hashKey, updates, expectedVersion, applied == 1)
+	return applied == 1, nil
+}
+
+// HashFieldExpire 设置哈希字段的过期时间(需Redis 7.4+)，按毫秒精度执行HPEXPIRE
+// 返回每个字段的状态码: -2-字段不存在, 0-条件不满足, 1-设置成功, 2-过期时间为0字段已被删除
+func (rc *redisClient) HashFieldExpire(hashKey string, ttl time.Duration, fields ...string) ([]int64, error) {
+	codes, err := rc.client().HPExpire(rc.ctx, hashKey, ttl, fields...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("设置哈希字段过期时间失败: %w", err)
+	}
+	log.Printf("哈希 %s 字段 %v 设置过期时间 %v: %v", hashKey, fields, ttl, codes)
+	return codes, nil
+}
+
+// HashDeleteField 在同一个pipeline中从多个哈希删除同一个字段，返回实际删除的字段总数
+func (rc *redisClient) HashDeleteField(field string, hashKeys ...string) (int64, error) {
+	cmds := make([]*redis.IntCmd, len(hashKeys))
+	_, err := rc.client().Pipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		for i, hashKey := range hashKeys {
+			cmds[i] = pipe.HDel(rc.ctx, hashKey, field)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("批量删除哈希字段失败: %w", err)
+	}
+
+	var deleted int64
+	for _, cmd := range cmds {
+		deleted += cmd.Val()
+	}
+	log.Printf("从 %d 个哈希中删除字段 %s: %d 个", len(hashKeys), field, deleted)
+	return deleted, nil
+}
+
+// HashPage 使用HSCAN分页遍历哈希字段，pageSize作为COUNT提示值，实际返回数量可能略有不同
+// 返回本页字段及下一页的游标，游标为0表示遍历结束；遍历期间一直存在的字段保证至少返回一次
+func (rc *redisClient) HashPage(hashKey string, cursor uint64, pageSize int64) (map[string]string, uint64, error) {
+	items, next, err := rc.client().HScan(rc.ctx, hashKey, cursor, "", pageSize).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("遍历哈希字段失败: %w", err)
+	}
+
+	fields := make(map[string]string, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		fields[items[i]] = items[i+1]
+	}
+	log.Printf("遍历哈希 %s: %d 个字段, 下一个游标: %d", hashKey, len(fields), next)
+	return fields, next, nil
+}
+
+// HashScanMatch 使用HSCAN MATCH遍历哈希，返回字段名匹配模式(如"attr:*")的所有字段
+func (rc *redisClient) HashScanMatch(hashKey, pattern string) (map[string]string, error) {
+	fields := make(map[string]string)
+	iter := rc.client().HScan(rc.ctx, hashKey, 0, pattern, 0).Iterator()
+	for iter.Next(rc.ctx) {
+		field := iter.Val()
+		if !iter.Next(rc.ctx) {
+			break
+		}
+		fields[field] = iter.Val()
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("遍历哈希字段失败: %w", err)
+	}
+	log.Printf("哈希 %s 中匹配 %s 的字段: %v", hashKey, pattern, fields)
+	return fields, nil
+}
+
+// HashDeleteAll 删除整个哈希，返回哈希删除前是否存在
+func (rc *redisClient) HashDeleteAll(hashKey string) (bool, error) {
+	deleted, err := rc.client().Del(rc.ctx, hashKey).Result()
+	if err != nil {
+		return false, fmt.Errorf("删除哈希失败: %w", err)
+	}
+	log.Printf("删除哈希 %s: %t", hashKey, deleted > 0)
+	return deleted > 0, nil
+}
+
+// renewLockScript 仅当锁仍由当前token持有时延长过期时间
+var renewLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLockScript 仅当锁仍由当前token持有时删除锁
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// AcquireLockWithRenewal 获取分布式锁，获取成功后每隔renewEvery将锁的过期时间重置为ttl，
+// 直到调用release为止；ttl至少为1ms，renewEvery必须大于0且小于ttl。锁已被占用时返回ok=false
+func (rc *redisClient) AcquireLockWithRenewal(key string, ttl, renewEvery time.Duration) (func(), bool, error) {
+	// 续期使用毫秒精度的PEXPIRE，不足1ms的ttl会变成PEXPIRE 0直接删除锁
+	if ttl < time.Millisecond {
+		return nil, false, fmt.Errorf("ttl 必须至少为 1ms")
+	}
+	if renewEvery <= 0 || renewEvery >= ttl {
+		return nil, false, fmt.Errorf("renewEvery 必须大于 0 且小于 ttl")
+	}
+
+	token, err := newLockToken()
+	if err != nil {
+		return nil, false, fmt.Errorf("生成锁标识失败: %w", err)
+	}
+
+	ok, err := rc.client().SetNX(rc.ctx, key, token, ttl).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("获取锁失败: %w", err)
+	}
+	if !ok {
+		log.Printf("锁 %s 已被占用", key)
+		return nil, false, nil
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(renewEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				renewed, err := renewLockScript.Run(rc.ctx, rc.client(), []string{key}, token, ttl.Milliseconds()).Int()
+				if err != nil {
+					log.Printf("锁 %s 续期失败: %v", key, err)
+				} else if renewed == 0 {
+					log.Printf("锁 %s 已丢失，停止续期", key)
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+			if err := releaseLockScript.Run(rc.ctx, rc.client(), []string{key}, token).Err(); err != nil {
+				log.Printf("释放锁 %s 失败: %v", key, err)
+				return
+			}
+			log.Printf("锁 %s 已释放", key)
+		})
+	}
+	log.Printf("获取锁成功: %s (过期时间: %v, 续期间隔: %v)", key, ttl, renewEvery)
+	return release, true, nil
+}
+
+// newLockToken 生成随机的锁标识，用于区分锁的持有者
+func newLockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Script 可复用的Lua脚本，执行时优先使用EVALSHA，避免每次都发送脚本内容
+type Script struct {
+	rc     *redisClient
+	script *redis.Script
+}
+
+// NewScript 创建可复用的Lua脚本
+func (rc *redisClient) NewScript(src string) *Script {
+	return &Script{
+		rc:     rc,
+		script: redis.NewScript(src),
+	}
+}
+
+// Run 执行脚本，优先使用EVALSHA；服务器返回NOSCRIPT(如执行过SCRIPT FLUSH)时自动使用EVAL重新加载
+// 脚本返回nil(Lua中的false)时结果为nil且不返回错误
+func (s *Script) Run(keys []string, args ...interface{}) (interface{}, error) {
+	result, err := s.script.Run(s.rc.ctx, s.rc.client(), keys, args...).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("执行脚本失败: %w", err)
+	}
+	return result, nil
+}
+
+// ScanEach 使用SCAN遍历匹配模式的键，对每个键调用fn，不会将所有键加载到内存中
+// count为每次SCAN的COUNT提示值；fn返回错误或context被取消时立即停止并返回该错误
+func (rc *redisClient) ScanEach(match string, count int64, fn func(key string) error) error {
+	var scanned int
+	iter := rc.client().Scan(rc.ctx, 0, match, count).Iterator()
+	for iter.Next(rc.ctx) {
+		if err := rc.ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(iter.Val()); err != nil {
+			return err
+		}
+		scanned++
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("扫描键失败: %w", err)
+	}
+	log.Printf("遍历匹配 %s 的键: %d 个", match, scanned)
+	return nil
+}
+
+// SetMembers 集合的所有元素，用于在ExportSnapshot/Import中区分集合与列表
+type SetMembers []string
+
+// Export 导出匹配模式的键及其值，用于调试和测试时检查键空间
+// 根据键的类型选择读取命令: string->string, list->[]string, hash->map[string]string,
+// set->[]string, zset->[]redis.Z；其他类型(如stream)会被跳过
+// 列表与集合都导出为[]string，Import会把其中的集合还原为列表，无法无损还原；需要用Import还原时请使用ExportSnapshot
+func (rc *redisClient) Export(pattern string) (map[string]interface{}, error) {
+	return rc.export(pattern, false)
+}
+
+// ExportSnapshot 与Export相同，但集合导出为SetMembers，结果可以交给Import原样还原
+func (rc *redisClient) ExportSnapshot(pattern string) (map[string]interface{}, error) {
+	return rc.export(pattern, true)
+}
+
+// export 导出匹配模式的键，snapshot为true时集合导出为SetMembers
+func (rc *redisClient) export(pattern string, snapshot bool) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	iter := rc.client().Scan(rc.ctx, 0, pattern, 0).Iterator()
+	for iter.Next(rc.ctx) {
+		key := iter.Val()
+		keyType, err := rc.client().Type(rc.ctx, key).Result()
+		if err != nil {
+			return nil, fmt.Errorf("获取键 %s 的类型失败: %w", key, err)
+		}
+
+		var value interface{}
+		switch keyType {
+		case "string":
+			value, err = rc.client().Get(rc.ctx, key).Result()
+		case "list":
+			value, err = rc.client().LRange(rc.ctx, key, 0, -1).Result()
+		case "hash":
+			value, err = rc.client().HGetAll(rc.ctx, key).Result()
+		case "set":
+			var members []string
+			members, err = rc.client().SMembers(rc.ctx, key).Result()
+			if snapshot {
+				value = SetMembers(members)
+			} else {
+				value = members
+			}
+		case "zset":
+			value, err = rc.client().ZRangeWithScores(rc.ctx, key, 0, -1).Result()
+		case "none":
+			// 扫描后键已被删除或过期
+			continue
+		default:
+			log.Printf("跳过不支持导出的键: %s (类型: %s)", key, keyType)
+			continue
+		}
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("读取键 %s 失败: %w", key, err)
+		}
+		data[key] = value
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("扫描键失败: %w", err)
+	}
+	log.Printf("导出匹配 %s 的键: %d 个", pattern, len(data))
+	return data, nil
+}
+
+// Import 将ExportSnapshot导出的数据写回Redis，已存在的同名键会被覆盖
+// 根据值的Go类型选择写入命令: string/[]byte->SET, []string->RPUSH(列表),
+// map[string]string->HSET, SetMembers->SADD, []redis.Z->ZADD；空集合会被跳过
+func (rc *redisClient) Import(data map[string]interface{}) error {
+	// 先校验所有值的类型和字符串值的大小，避免写入一部分后才发现不支持的值
+	for key, value := range data {
+		switch v := value.(type) {
+		case string:
+			if err := rc.checkValueSize(key, len(v)); err != nil {
+				return err
+			}
+		case []byte:
+			if err := rc.checkValueSize(key, len(v)); err != nil {
+				return err
+			}
+		case []string, map[string]string, SetMembers, []redis.Z:
+		default:
+			return fmt.Errorf("键 %s 的值类型 %T 不支持导入", key, value)
+		}
+	}
+
+	_, err := rc.client().Pipelined(rc.ctx, func(pipe redis.Pipeliner) error {
+		for key, value := range data {
+			pipe.Del(rc.ctx, key)
+			switch v := value.(type) {
+			case string:
+				pipe.Set(rc.ctx, key, v, 0)
+			case []byte:
+				pipe.Set(rc.ctx, key, v, 0)
+			case []string:
+				if len(v) > 0 {
+					pipe.RPush(rc.ctx, key, stringsToArgs(v)...)
+				}
+			case map[string]string:
+				if len(v) > 0 {
+					pipe.HSet(rc.ctx, key, v)
+				}
+			case SetMembers:
+				if len(v) > 0 {
+					pipe.SAdd(rc.ctx, key, stringsToArgs(v)...)
+				}
+			case []redis.Z:
+				if len(v) > 0 {
+					pipe.ZAdd(rc.ctx, key, v...)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("导入数据失败: %w", err)
+	}
+	log.Printf("导入成功: %d 个键", len(data))
+	return nil
+}
+
+// stringsToArgs 将字符串切片转换为命令参数
+func stringsToArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
+
+// ClientList 获取所有客户端连接信息，每个元素对应CLIENT LIST输出的一行，
+// 格式如"id=3 addr=127.0.0.1:50000 name= db=0 cmd=client|list ..."，不做进一步解析
+func (rc *redisClient) ClientList() ([]string, error) {
+	list, err := rc.client().ClientList(rc.ctx).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取客户端列表失败: %w", err)
+	}
+	lines := strings.Split(strings.TrimSpace(list), "\n")
+	log.Printf("客户端连接数量: %d", len(lines))
+	return lines, nil
+}
+
+// ClientKill 关闭指定地址(ip:port)的客户端连接
+func (rc *redisClient) ClientKill(addr string) error {
+	err := rc.client().ClientKill(rc.ctx, addr).Err()
+	if err != nil {
+		return fmt.Errorf("关闭客户端连接失败: %w", err)
+	}
+	log.Printf("已关闭客户端连接: %s", addr)
+	return nil
+}
+
+// Warmup 预先建立MinIdleConns个连接并放回连接池，避免流量高峰时临时建立连接
+// 仅支持单节点客户端，注入的其他类型客户端以及MinIdleConns为0时直接返回
+func (rc *redisClient) Warmup() error {
+	// 独占连接不经过客户端上添加的hook，需要单独检查是否已关闭
+	if rc.closed.Load() {
+		return ErrClientClosed
+	}
+	client, ok := rc.client().(*redis.Client)
+	if !ok || rc.config.MinIdleConns <= 0 {
+		return nil
+	}
+
+	// 同时持有多个独占连接，确保每次PING都使用不同的连接
+	conns := make([]*redis.Conn, 0, rc.config.MinIdleConns)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	for i := 0; i < rc.config.MinIdleConns; i++ {
+		conn := client.Conn()
+		conns = append(conns, conn)
+		if err := conn.Ping(rc.ctx).Err(); err != nil {
+			return fmt.Errorf("预热连接池失败: %w", err)
+		}
+	}
+	log.Printf("连接池预热完成: %d 个连接", len(conns))
+	return nil
+}
+
+// Time 获取Redis服务器时间，用于在多个节点之间使用统一的时间来源
+func (rc *redisClient) Time() (time.Time, error) {
+	serverTime, err := rc.client().Time(rc.ctx).Result()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("获取服务器时间失败: %w", err)
+	}
+	log.Printf("服务器时间: %v", serverTime)
+	return serverTime, nil
+}
+
+// DebugObject 获取键的内部调试信息(DEBUG OBJECT)，如编码方式和序列化长度
+// DEBUG命令仅用于测试，生产环境通常会被禁用
+func (rc *redisClient) DebugObject(key string) (string, error) {
+	info, err := rc.client().DebugObject(rc.ctx, key).Result()
+	if err != nil {
+		return "", fmt.Errorf("获取键调试信息失败: %w", err)
+	}
+	log.Printf("键 %s 的调试信息: %s", key, info)
+	return info, nil
+}
+
+// DebugSleep 让Redis服务器暂停指定时间(DEBUG SLEEP)，期间服务器不处理任何命令
+// DEBUG命令仅用于测试超时行为，切勿在生产环境使用；d超过ReadTimeout时本次调用会超时
+func (rc *redisClient) DebugSleep(d time.Duration) error {
+	err := rc.client().Do(rc.ctx, "DEBUG", "SLEEP", d.Seconds()).Err()
+	if err != nil {
+		return fmt.Errorf("DEBUG SLEEP执行失败: %w", err)
+	}
+	log.Printf("服务器已暂停: %v", d)
+	return nil
+}
+
+// subscription 一个活跃的订阅，Reset关闭旧连接池导致消息通道关闭时在新的底层客户端上重新订阅
+type subscription struct {
+	rc       *redisClient
+	ctx      context.Context
+	channels []string
+
+	mu      sync.Mutex
+	pubsub  *redis.PubSub
+	stopped bool // 已取消订阅，消息通道关闭后不再重新订阅
+}
+
+// subscribe 在当前的底层客户端上订阅频道并等待订阅确认，确保返回时已经开始接收消息
+func (rc *redisClient) subscribe(ctx context.Context, channels []string) (*subscription, error) {
+	pubsub, err := rc.newPubSub(ctx, channels)
+	if err != nil {
+		return nil, err
+	}
+	return &subscription{rc: rc, ctx: ctx, channels: channels, pubsub: pubsub}, nil
+}
+
+func (rc *redisClient) newPubSub(ctx context.Context, channels []string) (*redis.PubSub, error) {
+	pubsub := rc.client().Subscribe(ctx, channels...)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	return pubsub, nil
+}
+
+// channel 返回当前pubsub的消息通道
+func (s *subscription) channel() <-chan *redis.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pubsub.Channel()
+}
+
+// resubscribe 在消息通道关闭后调用，未取消订阅时说明Reset关闭了旧连接池，在新的底层客户端上重新订阅
+// 返回false表示已取消订阅、客户端已关闭、ctx已取消或重新订阅失败，订阅就此结束
+func (s *subscription) resubscribe() bool {
+	s.mu.Lock()
+	stopped := s.stopped
+	s.pubsub.Close()
+	s.mu.Unlock()
+	if stopped || s.rc.closed.Load() || s.ctx.Err() != nil {
+		return false
+	}
+
+	pubsub, err := s.rc.newPubSub(s.ctx, s.channels)
+	if err != nil {
+		log.Printf("重新订阅频道 %v 失败: %v", s.channels, err)
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		pubsub.Close()
+		return false
+	}
+	s.pubsub = pubsub
+	log.Printf("重新订阅频道成功: %v", s.channels)
+	return true
+}
+
+// stop 取消订阅并关闭pubsub，之后消息通道关闭
+func (s *subscription) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	if err := s.pubsub.Unsubscribe(s.rc.ctx, s.channels...); err != nil {
+		log.Printf("取消订阅失败: %v", err)
+	}
+	s.pubsub.Close()
+}
+
+// SubscribeHandler 订阅频道并在后台回调处理消息，返回停止订阅的函数
+// 连接断开时go-redis会自动重连并重新订阅；Reset后在新的连接池上重新订阅，切换期间发布的消息可能丢失
+func (rc *redisClient) SubscribeHandler(channels []string, handler func(channel, payload string)) (func(), error) {
+	if rc.closed.Load() {
+		return nil, ErrClientClosed
+	}
+	sub, err := rc.subscribe(rc.ctx, channels)
+	if err != nil {
+		return nil, fmt.Errorf("订阅频道失败: %w", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			for msg := range sub.channel() {
+				handler(msg.Channel, msg.Payload)
+			}
+			if !sub.resubscribe() {
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			sub.stop()
+			<-done
+			log.Printf("已停止订阅频道: %v", channels)
+		})
+	}
+	rc.OnClose(stop)
+	log.Printf("订阅频道成功: %v", channels)
+	return stop, nil
+}
+
+// OnInvalidation 订阅缓存失效频道，频道中发布的每条消息视为一个失效的键名并回调handler
+// 适用于通过pub/sub通知各进程清理本地缓存，返回的stop与SubscribeHandler相同
+func (rc *redisClient) OnInvalidation(channel string, handler func(key string)) (func(), error) {
+	return rc.SubscribeHandler([]string{channel}, func(_, key string) {
+		handler(key)
+	})
+}
+
+// SubscribeContext 订阅频道并返回只读消息通道，ctx取消或客户端关闭时自动取消订阅并关闭通道
+// Reset后与SubscribeHandler一样在新的连接池上重新订阅，重新订阅失败时关闭通道
+func (rc *redisClient) SubscribeContext(ctx context.Context, channels ...string) (<-chan *redis.Message, error) {
+	if rc.closed.Load() {
+		return nil, ErrClientClosed
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	sub, err := rc.subscribe(ctx, channels)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("订阅频道失败: %w", err)
+	}
+
+	out := make(chan *redis.Message)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer cancel()
+		defer close(out)
+		msgs := sub.channel()
+		for {
+			select {
+			case <-ctx.Done():
+				// ctx已取消，stop使用基础context取消订阅
+				sub.stop()
+				log.Printf("已停止订阅频道: %v", channels)
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					if !sub.resubscribe() {
+						return
+					}
+					msgs = sub.channel()
+					continue
+				}
+				select {
+				case out <- msg:
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+	rc.OnClose(func() {
+		cancel()
+		<-done
+	})
+	log.Printf("订阅频道成功: %v", channels)
+	return out, nil
+}
+
+// BulkLoader 批量写入器，将写入命令缓存在pipeline中，达到批次大小时自动提交
+// 提交是同步进行的，写入速度会受Redis处理速度限制，从而形成背压；非并发安全
+// 待提交的命令缓存在内存中，提交时才在当前的底层客户端上创建pipeline，因此Reset后仍可继续使用
+type BulkLoader struct {
+	rc      *redisClient
+	pending []SetEntry
+	size    int
+	flushes int
+	err     error
+}
+
+// BulkLoad 创建按批次自动提交的批量写入器，size为每批命令数量
+func (rc *redisClient) BulkLoad(size int) *BulkLoader {
+	if size <= 0 {
+		size = 1
+	}
+	return &BulkLoader{
+		rc:      rc,
+		pending: make([]SetEntry, 0, size),
+		size:    size,
+	}
+}
+
+// Set 缓存一条写入命令，达到批次大小时自动提交，提交错误在Flush时返回
+// 值超过MaxValueBytes时跳过该条写入，ErrValueTooLarge同样在Flush时返回
+func (bl *BulkLoader) Set(key, value string) {
+	if err := bl.rc.checkValueSize(key, len(value)); err != nil {
+		if bl.err == nil {
+			bl.err = err
+		}
+		return
+	}
+	bl.pending = append(bl.pending, SetEntry{Key: key, Value: value})
+	if len(bl.pending) >= bl.size {
+		bl.flush()
+	}
+}
+
+// Flush 提交剩余的写入命令，返回批量写入过程中遇到的第一个错误
+func (bl *BulkLoader) Flush() error {
+	bl.flush()
+	if bl.err != nil {
+		return fmt.Errorf("批量写入失败: %w", bl.err)
+	}
+	return nil
+}
+
+// Flushes 返回已提交的批次数量
+func (bl *BulkLoader) Flushes() int {
+	return bl.flushes
+}
+
+func (bl *BulkLoader) flush() {
+	if len(bl.pending) == 0 {
+		return
+	}
+	_, err := bl.rc.client().Pipelined(bl.rc.ctx, func(pipe redis.Pipeliner) error {
+		for _, entry := range bl.pending {
+			pipe.Set(bl.rc.ctx, entry.Key, entry.Value, entry.TTL)
+		}
+		return nil
+	})
+	if err != nil {
+		if bl.err == nil {
+			bl.err = err
+		}
+	} else {
+		log.Printf("批量写入提交成功: %d 条命令", len(bl.pending))
+	}
+	bl.pending = bl.pending[:0]
+	bl.flushes++
+}
+
+// Reset 关闭当前的连接池，按创建时的配置重新创建底层客户端并重新添加所有hook，用于连接出现异常后恢复
+// WithTimeout/WithContext返回的拷贝共享底层客户端，同样会切换到新的连接池；正在执行的命令可能因旧连接池关闭而失败
+// SubscribeHandler/SubscribeContext/OnInvalidation的订阅会在新的连接池上重新订阅，BulkLoader下一次提交时使用新的连接池
+// 通过NewRedisClientFromClient注入的客户端无法重新创建，返回错误
+func (rc *redisClient) Reset() error {
+	if rc.closed.Load() {
+		return ErrClientClosed
+	}
+	if rc.ref.factory == nil {
+		return fmt.Errorf("注入的客户端不支持Reset")
+	}
+
+	client := rc.ref.factory()
+	rc.addBaseHooks(client)
+	rc.addConfigHooks(client)
+	if !rc.config.LazyConnect {
+		if err := pingWithTimeout(client, rc.config.ConnectTimeout); err != nil {
+			client.Close()
+			return fmt.Errorf("重新连接Redis失败: %w", err)
+		}
+	}
+
+	rc.ref.mu.Lock()
+	old := rc.ref.current
+	rc.ref.current = client
+	rc.ref.mu.Unlock()
+	old.Close()
+
+	// 重新创建期间客户端可能已被Close，此时新客户端也需要关闭
+	if rc.closed.Load() {
+		client.Close()
+		return ErrClientClosed
+	}
+	log.Println("Redis客户端已重置")
+	return nil
+}
+
+// OnClose 注册关闭客户端时执行的清理函数，Close时按注册的相反顺序(后注册先执行)在关闭连接前调用，
+// 执行期间客户端仍可正常使用，可用于释放锁、停止订阅和健康检查等后台goroutine；Close已开始时立即执行fn
+func (rc *redisClient) OnClose(fn func()) {
+	rc.onClose.mu.Lock()
+	if !rc.onClose.closing {
+		rc.onClose.fns = append(rc.onClose.fns, fn)
+		rc.onClose.mu.Unlock()
+		return
+	}
+	rc.onClose.mu.Unlock()
+	fn()
+}
+
+// Close 先执行OnClose注册的清理函数，再关闭Redis连接，重复调用是安全的；关闭后调用任何方法都会返回ErrClientClosed
 func (rc *redisClient) Close() {
-	if rc.client != nil {
-		rc.client.Close()
-		log.Println("Redis连接已关闭")
+	if rc.ref == nil {
+		return
+	}
+
+	rc.onClose.mu.Lock()
+	if rc.onClose.closing {
+		rc.onClose.mu.Unlock()
+		return
+	}
+	rc.onClose.closing = true
+	fns := rc.onClose.fns
+	rc.onClose.fns = nil
+	rc.onClose.mu.Unlock()
+
+	// 清理函数可能还需要执行命令(如释放锁)，因此在标记关闭之前执行
+	for i := len(fns) - 1; i >= 0; i-- {
+		fns[i]()
 	}
+	rc.closed.Store(true)
+	rc.client().Close()
+	log.Println("Redis连接已关闭")
 }
 
 func GetRedisClient(rc *redisClient) *redisClient {
@@ -0,0 +1,3006 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMain(m *testing.M) {
+	// 客户端的每个操作都会打印日志，测试时丢弃
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// newTestClient 创建连接到miniredis的客户端，测试结束时自动关闭
+func newTestClient(t *testing.T) (*redisClient, *miniredis.Miniredis) {
+	t.Helper()
+	m := miniredis.RunT(t)
+	rc := NewRedisClientFromClient(redis.NewClient(&redis.Options{Addr: m.Addr()}), context.Background())
+	t.Cleanup(rc.Close)
+	return rc, m
+}
+
+// newConfigTestClient 按config创建连接到miniredis的客户端(Addr由miniredis填充)，测试结束时自动关闭
+func newConfigTestClient(t *testing.T, config *RedisConfig) (*redisClient, *miniredis.Miniredis) {
+	t.Helper()
+	m := miniredis.RunT(t)
+	config.Addr = m.Addr()
+	rc, err := NewRedisClient(config, context.Background())
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	t.Cleanup(rc.Close)
+	return rc, m
+}
+
+// newRealRedisClient 创建连接到真实Redis的客户端，用于miniredis不支持的命令
+// 需要设置REDIS_TEST_ADDR(如"127.0.0.1:6379")，未设置时跳过测试；会清空该Redis的0号数据库
+func newRealRedisClient(t *testing.T) *redisClient {
+	t.Helper()
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("未设置REDIS_TEST_ADDR，跳过需要真实Redis的测试")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := client.FlushDB(context.Background()).Err(); err != nil {
+		t.Fatalf("清空测试数据库失败: %v", err)
+	}
+	rc := NewRedisClientFromClient(client, context.Background())
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+func TestNewRedisClientFromClient(t *testing.T) {
+	rc, _ := newTestClient(t)
+
+	if err := rc.Set("greeting", "hello", 0); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+	value, err := rc.Get("greeting")
+	if err != nil {
+		t.Fatalf("Get失败: %v", err)
+	}
+	if value != "hello" {
+		t.Fatalf("Get = %q, 期望 %q", value, "hello")
+	}
+}
+
+func TestSubscribeHandler(t *testing.T) {
+	rc, _ := newTestClient(t)
+
+	received := make(chan string, 2)
+	stop, err := rc.SubscribeHandler([]string{"news"}, func(channel, payload string) {
+		received <- channel + ":" + payload
+	})
+	if err != nil {
+		t.Fatalf("SubscribeHandler失败: %v", err)
+	}
+	for _, payload := range []string{"first", "second"} {
+		if err := rc.client().Publish(context.Background(), "news", payload).Err(); err != nil {
+			t.Fatalf("发布消息失败: %v", err)
+		}
+	}
+
+	for _, want := range []string{"news:first", "news:second"} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("收到消息 %q, 期望 %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("等待消息 %q 超时", want)
+		}
+	}
+	stop()
+}
+
+func TestSetZUnion(t *testing.T) {
+	rc, m := newTestClient(t)
+
+	if err := rc.SetZAdd("z1", redis.Z{Score: 1, Member: "a"}, redis.Z{Score: 2, Member: "b"}); err != nil {
+		t.Fatalf("SetZAdd失败: %v", err)
+	}
+	if err := rc.SetZAdd("z2", redis.Z{Score: 3, Member: "b"}, redis.Z{Score: 4, Member: "c"}); err != nil {
+		t.Fatalf("SetZAdd失败: %v", err)
+	}
+
+	members, err := rc.SetZUnion(&redis.ZStore{Keys: []string{"z1", "z2"}})
+	if err != nil {
+		t.Fatalf("SetZUnion失败: %v", err)
+	}
+	// 并集按合并后的分数升序: a=1, c=4, b=5
+	if want := []string{"a", "c", "b"}; !reflect.DeepEqual(members, want) {
+		t.Fatalf("SetZUnion = %v, 期望 %v", members, want)
+	}
+	// 不存储结果，只存在原来的两个键
+	if keys := m.Keys(); !reflect.DeepEqual(keys, []string{"z1", "z2"}) {
+		t.Fatalf("键列表 = %v, 期望只有 z1 和 z2", keys)
+	}
+}
+
+func TestDeleteReport(t *testing.T) {
+	rc, m := newTestClient(t)
+
+	for _, key := range []string{"k1", "k2", "k3"} {
+		m.Set(key, "v")
+	}
+	deleted, err := rc.DeleteReport("k1", "missing1", "k2", "missing2", "k3")
+	if err != nil {
+		t.Fatalf("DeleteReport失败: %v", err)
+	}
+	if want := []string{"k1", "k2", "k3"}; !reflect.DeepEqual(deleted, want) {
+		t.Fatalf("DeleteReport = %v, 期望 %v", deleted, want)
+	}
+	if keys := m.Keys(); len(keys) != 0 {
+		t.Fatalf("删除后仍存在键: %v", keys)
+	}
+}
+
+func TestSetIfNewer(t *testing.T) {
+	rc, m := newTestClient(t)
+
+	steps := []struct {
+		version int64
+		value   string
+		written bool
+		stored  string
+	}{
+		{version: 5, value: "v5", written: true, stored: "v5"},  // 首次写入
+		{version: 3, value: "v3", written: false, stored: "v5"}, // 旧版本被拒绝
+		{version: 8, value: "v8", written: true, stored: "v8"},  // 新版本覆盖
+	}
+	for _, step := range steps {
+		written, err := rc.SetIfNewer("doc", step.version, step.value, time.Minute)
+		if err != nil {
+			t.Fatalf("SetIfNewer(版本 %d)失败: %v", step.version, err)
+		}
+		if written != step.written {
+			t.Fatalf("SetIfNewer(版本 %d) = %t, 期望 %t", step.version, written, step.written)
+		}
+		if got := m.HGet("doc", "value"); got != step.stored {
+			t.Fatalf("版本 %d 之后存储的值 = %q, 期望 %q", step.version, got, step.stored)
+		}
+	}
+	if ttl := m.TTL("doc"); ttl <= 0 {
+		t.Fatalf("TTL = %v, 期望大于0", ttl)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.Set("key", "value")
+
+	_, err := rc.WithTimeout(time.Nanosecond).Get("key")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("1ns超时的Get错误 = %v, 期望 context.DeadlineExceeded", err)
+	}
+	// 原客户端不受单次操作超时影响
+	value, err := rc.Get("key")
+	if err != nil {
+		t.Fatalf("原客户端Get失败: %v", err)
+	}
+	if value != "value" {
+		t.Fatalf("Get = %q, 期望 %q", value, "value")
+	}
+}
+
+func TestExpireWithFlagGT(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.Set("key", "value")
+	m.SetTTL("key", 100*time.Second)
+
+	// GT: 50s小于当前的100s，不修改
+	ok, err := rc.ExpireWithFlag("key", 50*time.Second, "GT")
+	if err != nil {
+		t.Fatalf("ExpireWithFlag(GT 50s)失败: %v", err)
+	}
+	if ok {
+		t.Fatal("ExpireWithFlag(GT 50s) = true, 期望 false")
+	}
+	if ttl := m.TTL("key"); ttl != 100*time.Second {
+		t.Fatalf("GT 50s之后 TTL = %v, 期望 100s", ttl)
+	}
+
+	// GT: 200s大于当前的100s，修改生效
+	ok, err = rc.ExpireWithFlag("key", 200*time.Second, "GT")
+	if err != nil {
+		t.Fatalf("ExpireWithFlag(GT 200s)失败: %v", err)
+	}
+	if !ok {
+		t.Fatal("ExpireWithFlag(GT 200s) = false, 期望 true")
+	}
+	if ttl := m.TTL("key"); ttl != 200*time.Second {
+		t.Fatalf("GT 200s之后 TTL = %v, 期望 200s", ttl)
+	}
+}
+
+func TestBulkLoader(t *testing.T) {
+	rc, m := newTestClient(t)
+
+	bl := rc.BulkLoad(100)
+	for i := 0; i < 1000; i++ {
+		bl.Set(fmt.Sprintf("bulk:%d", i), strconv.Itoa(i))
+	}
+	if err := bl.Flush(); err != nil {
+		t.Fatalf("Flush失败: %v", err)
+	}
+	if got := bl.Flushes(); got != 10 {
+		t.Fatalf("Flushes = %d, 期望 10", got)
+	}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("bulk:%d", i)
+		if !m.Exists(key) {
+			t.Fatalf("键 %s 不存在", key)
+		}
+	}
+}
+
+func TestSetZPopMinCount(t *testing.T) {
+	rc, _ := newTestClient(t)
+
+	if err := rc.SetZAdd("scores",
+		redis.Z{Score: 5, Member: "e"},
+		redis.Z{Score: 1, Member: "a"},
+		redis.Z{Score: 4, Member: "d"},
+		redis.Z{Score: 2, Member: "b"},
+		redis.Z{Score: 3, Member: "c"},
+	); err != nil {
+		t.Fatalf("SetZAdd失败: %v", err)
+	}
+
+	popped, err := rc.SetZPopMinCount("scores", 3)
+	if err != nil {
+		t.Fatalf("SetZPopMinCount失败: %v", err)
+	}
+	want := []redis.Z{{Score: 1, Member: "a"}, {Score: 2, Member: "b"}, {Score: 3, Member: "c"}}
+	if !reflect.DeepEqual(popped, want) {
+		t.Fatalf("SetZPopMinCount = %v, 期望 %v", popped, want)
+	}
+	card, err := rc.SetZCard("scores")
+	if err != nil {
+		t.Fatalf("SetZCard失败: %v", err)
+	}
+	if card != 2 {
+		t.Fatalf("弹出后 SetZCard = %d, 期望 2", card)
+	}
+}
+
+// opRecord 审计回调收到的一次调用
+type opRecord struct {
+	op, key string
+	err     error
+}
+
+func TestOpHooks(t *testing.T) {
+	var before, after []opRecord
+	rc, m := newConfigTestClient(t, &RedisConfig{
+		BeforeOp: func(op, key string) { before = append(before, opRecord{op: op, key: key}) },
+		AfterOp:  func(op, key string, err error) { after = append(after, opRecord{op: op, key: key, err: err}) },
+	})
+	reset := func() { before, after = nil, nil }
+
+	reset()
+	if err := rc.Set("user:1", "alice", 0); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+	if want := []opRecord{{op: "SET", key: "user:1"}}; !reflect.DeepEqual(before, want) || !reflect.DeepEqual(after, want) {
+		t.Fatalf("Set回调 before=%v after=%v, 期望 %v", before, after, want)
+	}
+
+	// 命令的错误传递给AfterOp
+	reset()
+	if _, err := rc.Increment("user:1"); err == nil {
+		t.Fatal("对非数字值Increment未返回错误")
+	}
+	if len(after) != 1 || after[0].op != "INCR" || after[0].key != "user:1" || after[0].err == nil {
+		t.Fatalf("Increment的AfterOp = %v, 期望带错误的 INCR user:1", after)
+	}
+
+	// 多键命令对每个键各触发一次
+	reset()
+	m.Set("a", "1")
+	m.Set("b", "2")
+	if _, err := rc.DeleteReport("a", "b"); err != nil {
+		t.Fatalf("DeleteReport失败: %v", err)
+	}
+	if err := rc.client().Del(context.Background(), "a", "b").Err(); err != nil {
+		t.Fatalf("DEL失败: %v", err)
+	}
+	wantKeys := []opRecord{{op: "DEL", key: "a"}, {op: "DEL", key: "b"}, {op: "DEL", key: "a"}, {op: "DEL", key: "b"}}
+	if !reflect.DeepEqual(after, wantKeys) {
+		t.Fatalf("DEL的AfterOp = %v, 期望 %v", after, wantKeys)
+	}
+
+	// 脚本命令报告KEYS而不是SHA/脚本内容，首次执行时的NOSCRIPT不报告
+	reset()
+	script := rc.NewScript("return redis.call('GET', KEYS[1])")
+	if _, err := script.Run([]string{"user:1"}); err != nil {
+		t.Fatalf("执行脚本失败: %v", err)
+	}
+	for _, rec := range after {
+		if rec.key != "user:1" || rec.err != nil {
+			t.Fatalf("脚本的AfterOp = %v, 期望只报告成功的 user:1", after)
+		}
+	}
+	if len(after) != 1 {
+		t.Fatalf("脚本的AfterOp = %v, 期望只有一次", after)
+	}
+
+	// 事务的MULTI/EXEC不触发回调
+	reset()
+	if err := rc.HashSetWithTTL("h", map[string]string{"f": "v"}, time.Minute); err != nil {
+		t.Fatalf("HashSetWithTTL失败: %v", err)
+	}
+	want := []opRecord{{op: "HSET", key: "h"}, {op: "EXPIRE", key: "h"}}
+	if !reflect.DeepEqual(after, want) {
+		t.Fatalf("事务的AfterOp = %v, 期望 %v", after, want)
+	}
+}
+
+func TestSetZRankWithScore(t *testing.T) {
+	rc, _ := newTestClient(t)
+
+	if err := rc.SetZAdd("board",
+		redis.Z{Score: 10, Member: "alice"},
+		redis.Z{Score: 20, Member: "bob"},
+		redis.Z{Score: 30, Member: "carol"},
+	); err != nil {
+		t.Fatalf("SetZAdd失败: %v", err)
+	}
+	rank, score, err := rc.SetZRankWithScore("board", "bob")
+	if err != nil {
+		t.Fatalf("SetZRankWithScore失败: %v", err)
+	}
+	if rank != 1 || score != 20 {
+		t.Fatalf("SetZRankWithScore = (%d, %v), 期望 (1, 20)", rank, score)
+	}
+}
+
+func TestRetryHook(t *testing.T) {
+	policy := &RetryPolicy{Attempts: 4, BaseDelay: 10 * time.Millisecond, MaxDelay: 25 * time.Millisecond, Jitter: 5 * time.Millisecond}
+
+	// 模拟前3次网络错误、第4次成功的命令执行
+	var calls []time.Time
+	next := func(ctx context.Context, cmd redis.Cmder) error {
+		calls = append(calls, time.Now())
+		if len(calls) <= 3 {
+			return errors.New("connection reset by peer")
+		}
+		return nil
+	}
+	ctx := context.Background()
+	if err := (retryHook{policy: policy}).ProcessHook(next)(ctx, redis.NewStringCmd(ctx, "get", "key")); err != nil {
+		t.Fatalf("重试后仍失败: %v", err)
+	}
+	if len(calls) != 4 {
+		t.Fatalf("执行次数 = %d, 期望 4", len(calls))
+	}
+	// 每次等待至少为指数退避的基础时间(不超过上限)，且不明显超过MaxDelay
+	for i := 1; i < len(calls); i++ {
+		gap := calls[i].Sub(calls[i-1])
+		lower := policy.BaseDelay << uint(i-1)
+		if lower > policy.MaxDelay {
+			lower = policy.MaxDelay
+		}
+		if gap < lower || gap > policy.MaxDelay+20*time.Millisecond {
+			t.Fatalf("第 %d 次重试前等待 %v, 期望在 [%v, %v] 附近", i, gap, lower, policy.MaxDelay)
+		}
+	}
+
+	// 非幂等命令不重试
+	calls = nil
+	if err := (retryHook{policy: policy}).ProcessHook(next)(ctx, redis.NewIntCmd(ctx, "incr", "key")); err == nil {
+		t.Fatal("非幂等命令的错误被重试掉了")
+	}
+	if len(calls) != 1 {
+		t.Fatalf("非幂等命令执行次数 = %d, 期望 1", len(calls))
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &RetryPolicy{Attempts: 10, BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, Jitter: 5 * time.Millisecond}
+
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		delay := policy.backoff(0)
+		if delay < policy.BaseDelay || delay >= policy.BaseDelay+policy.Jitter {
+			t.Fatalf("backoff(0) = %v, 期望在 [%v, %v) 内", delay, policy.BaseDelay, policy.BaseDelay+policy.Jitter)
+		}
+		seen[delay] = true
+	}
+	if len(seen) < 2 {
+		t.Fatal("backoff(0) 100次结果完全相同，没有抖动")
+	}
+	// 退避时间加上抖动也不超过MaxDelay
+	for attempt := 0; attempt < 70; attempt++ {
+		if delay := policy.backoff(attempt); delay > policy.MaxDelay {
+			t.Fatalf("backoff(%d) = %v, 超过上限 %v", attempt, delay, policy.MaxDelay)
+		}
+	}
+}
+
+func TestHashGetTyped(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.HSet("item", "count", "42", "price", "3.14", "active", "true", "bad", "abc")
+
+	n, err := rc.HashGetInt("item", "count")
+	if err != nil || n != 42 {
+		t.Fatalf("HashGetInt = (%d, %v), 期望 (42, nil)", n, err)
+	}
+	f, err := rc.HashGetFloat("item", "price")
+	if err != nil || f != 3.14 {
+		t.Fatalf("HashGetFloat = (%v, %v), 期望 (3.14, nil)", f, err)
+	}
+	b, err := rc.HashGetBool("item", "active")
+	if err != nil || !b {
+		t.Fatalf("HashGetBool = (%t, %v), 期望 (true, nil)", b, err)
+	}
+
+	// 格式错误的值返回包装了strconv.ErrSyntax的错误
+	if _, err := rc.HashGetInt("item", "bad"); !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatalf("HashGetInt(格式错误) 错误 = %v, 期望包装 strconv.ErrSyntax", err)
+	}
+	if _, err := rc.HashGetFloat("item", "bad"); !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatalf("HashGetFloat(格式错误) 错误 = %v, 期望包装 strconv.ErrSyntax", err)
+	}
+	if _, err := rc.HashGetBool("item", "bad"); !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatalf("HashGetBool(格式错误) 错误 = %v, 期望包装 strconv.ErrSyntax", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.Set("app:str", "value")
+	m.RPush("app:list", "a", "b")
+	m.HSet("app:hash", "f", "v")
+	m.SetAdd("app:set", "x", "y")
+	m.ZAdd("app:zset", 1, "m")
+	m.Set("other", "not exported")
+
+	data, err := rc.Export("app:*")
+	if err != nil {
+		t.Fatalf("Export失败: %v", err)
+	}
+	if len(data) != 5 {
+		t.Fatalf("Export 导出 %d 个键, 期望 5: %v", len(data), data)
+	}
+	if v, ok := data["app:str"].(string); !ok || v != "value" {
+		t.Fatalf("app:str = %#v, 期望 string \"value\"", data["app:str"])
+	}
+	if v, ok := data["app:list"].([]string); !ok || !reflect.DeepEqual(v, []string{"a", "b"}) {
+		t.Fatalf("app:list = %#v, 期望 []string{a b}", data["app:list"])
+	}
+	if v, ok := data["app:hash"].(map[string]string); !ok || !reflect.DeepEqual(v, map[string]string{"f": "v"}) {
+		t.Fatalf("app:hash = %#v, 期望 map[string]string{f:v}", data["app:hash"])
+	}
+	members, ok := data["app:set"].([]string)
+	sort.Strings(members)
+	if !ok || !reflect.DeepEqual(members, []string{"x", "y"}) {
+		t.Fatalf("app:set = %#v, 期望 []string{x y}", data["app:set"])
+	}
+	if v, ok := data["app:zset"].([]redis.Z); !ok || !reflect.DeepEqual(v, []redis.Z{{Score: 1, Member: "m"}}) {
+		t.Fatalf("app:zset = %#v, 期望 []redis.Z{{1 m}}", data["app:zset"])
+	}
+}
+
+func TestExportSnapshotImportRoundTrip(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.Set("app:str", "value")
+	m.RPush("app:list", "a", "b", "a")
+	m.HSet("app:hash", "f1", "v1", "f2", "v2")
+	m.SetAdd("app:set", "x", "y")
+	m.ZAdd("app:zset", 1, "m1")
+	m.ZAdd("app:zset", 2, "m2")
+
+	snapshot, err := rc.ExportSnapshot("app:*")
+	if err != nil {
+		t.Fatalf("ExportSnapshot失败: %v", err)
+	}
+	m.FlushAll()
+	if err := rc.Import(snapshot); err != nil {
+		t.Fatalf("Import失败: %v", err)
+	}
+
+	restored, err := rc.ExportSnapshot("app:*")
+	if err != nil {
+		t.Fatalf("ExportSnapshot失败: %v", err)
+	}
+	// 集合元素的顺序不固定，排序后比较
+	for _, data := range []map[string]interface{}{snapshot, restored} {
+		sort.Strings(data["app:set"].(SetMembers))
+	}
+	if !reflect.DeepEqual(restored, snapshot) {
+		t.Fatalf("还原后的数据 = %v, 期望 %v", restored, snapshot)
+	}
+	// 键的类型与原来一致
+	for key, want := range map[string]string{"app:str": "string", "app:list": "list", "app:hash": "hash", "app:set": "set", "app:zset": "zset"} {
+		if got := m.Type(key); got != want {
+			t.Fatalf("还原后 %s 的类型 = %s, 期望 %s", key, got, want)
+		}
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.Set("app:str", "value")
+	m.RPush("app:list", "a", "b", "a")
+	m.HSet("app:hash", "f1", "v1", "f2", "v2")
+	m.SetAdd("app:set", "x", "y")
+	m.ZAdd("app:zset", 1, "m1")
+
+	data, err := rc.Export("app:*")
+	if err != nil {
+		t.Fatalf("Export失败: %v", err)
+	}
+	if err := rc.client().FlushDB(context.Background()).Err(); err != nil {
+		t.Fatalf("FlushDB失败: %v", err)
+	}
+	if err := rc.Import(data); err != nil {
+		t.Fatalf("Import失败: %v", err)
+	}
+
+	// 字符串、列表、哈希和有序集合可以原样还原
+	restored, err := rc.Export("app:*")
+	if err != nil {
+		t.Fatalf("Export失败: %v", err)
+	}
+	for _, key := range []string{"app:str", "app:list", "app:hash", "app:zset"} {
+		if !reflect.DeepEqual(restored[key], data[key]) {
+			t.Fatalf("还原后 %s = %#v, 期望 %#v", key, restored[key], data[key])
+		}
+	}
+	// 集合导出为[]string，Import后变成了列表
+	if got := m.Type("app:set"); got != "list" {
+		t.Fatalf("Export导出的集合经Import还原后的类型 = %s, 期望 list", got)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.Set("key", "value")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := rc.WithContext(ctx).Get("key"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("已取消context的Get错误 = %v, 期望 context.Canceled", err)
+	}
+	// 原客户端仍使用自己的context
+	if value, err := rc.Get("key"); err != nil || value != "value" {
+		t.Fatalf("原客户端Get = (%q, %v), 期望 (\"value\", nil)", value, err)
+	}
+
+	// 调用方context中的值传递到hook链
+	hook := &ctxValueHook{key: ctxKey("request-id")}
+	rc.client().AddHook(hook)
+	reqCtx := context.WithValue(context.Background(), ctxKey("request-id"), "req-42")
+	if _, err := rc.WithContext(reqCtx).Get("key"); err != nil {
+		t.Fatalf("Get失败: %v", err)
+	}
+	if hook.seen != "req-42" {
+		t.Fatalf("hook看到的context值 = %v, 期望 req-42", hook.seen)
+	}
+}
+
+type ctxKey string
+
+// ctxValueHook 记录命令执行时context中key对应的值
+type ctxValueHook struct {
+	key  ctxKey
+	seen interface{}
+}
+
+func (h *ctxValueHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *ctxValueHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.seen = ctx.Value(h.key)
+		return next(ctx, cmd)
+	}
+}
+
+func (h *ctxValueHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestSetZRemRangeByRank(t *testing.T) {
+	tests := []struct {
+		start, stop int64
+		removed     int64
+		remaining   []string
+	}{
+		{start: 0, stop: -1, removed: 5, remaining: []string{}},
+		{start: 0, stop: 0, removed: 1, remaining: []string{"b", "c", "d", "e"}},
+		{start: -1, stop: -1, removed: 1, remaining: []string{"a", "b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%d", tt.start, tt.stop), func(t *testing.T) {
+			rc, _ := newTestClient(t)
+			for i, member := range []string{"a", "b", "c", "d", "e"} {
+				if err := rc.SetZAdd("z", redis.Z{Score: float64(i), Member: member}); err != nil {
+					t.Fatalf("SetZAdd失败: %v", err)
+				}
+			}
+
+			removed, err := rc.SetZRemRangeByRank("z", tt.start, tt.stop)
+			if err != nil {
+				t.Fatalf("SetZRemRangeByRank失败: %v", err)
+			}
+			if removed != tt.removed {
+				t.Fatalf("SetZRemRangeByRank = %d, 期望 %d", removed, tt.removed)
+			}
+			remaining, err := rc.SetZRange("z", 0, -1)
+			if err != nil {
+				t.Fatalf("SetZRange失败: %v", err)
+			}
+			if !reflect.DeepEqual(remaining, tt.remaining) {
+				t.Fatalf("剩余元素 = %v, 期望 %v", remaining, tt.remaining)
+			}
+		})
+	}
+}
+
+func TestAcquireLockWithRenewal(t *testing.T) {
+	rc, m := newTestClient(t)
+
+	// 参数不合法时返回错误且不获取锁
+	invalid := []struct{ ttl, renewEvery time.Duration }{
+		{ttl: 0, renewEvery: time.Second},
+		{ttl: time.Microsecond, renewEvery: time.Nanosecond},
+		{ttl: time.Second, renewEvery: 0},
+		{ttl: time.Second, renewEvery: time.Second},
+		{ttl: time.Second, renewEvery: 2 * time.Second},
+	}
+	for _, tt := range invalid {
+		if _, _, err := rc.AcquireLockWithRenewal("lock", tt.ttl, tt.renewEvery); err == nil {
+			t.Fatalf("AcquireLockWithRenewal(ttl=%v, renewEvery=%v) 未返回错误", tt.ttl, tt.renewEvery)
+		}
+		if m.Exists("lock") {
+			t.Fatalf("参数不合法(ttl=%v, renewEvery=%v)时仍写入了锁", tt.ttl, tt.renewEvery)
+		}
+	}
+
+	release, ok, err := rc.AcquireLockWithRenewal("lock", 200*time.Millisecond, 100*time.Millisecond)
+	if err != nil || !ok {
+		t.Fatalf("AcquireLockWithRenewal = (%t, %v), 期望获取成功", ok, err)
+	}
+
+	// miniredis只在FastForward时让键过期，持有锁的1秒内让它的时钟随真实时间前进，
+	// 没有续期的话锁会在200ms后过期
+	stopClock := make(chan struct{})
+	clockDone := make(chan struct{})
+	go func() {
+		defer close(clockDone)
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopClock:
+				return
+			case <-ticker.C:
+				m.FastForward(10 * time.Millisecond)
+			}
+		}
+	}()
+	time.Sleep(time.Second)
+	close(stopClock)
+	<-clockDone
+
+	if !m.Exists("lock") {
+		t.Fatal("持有锁超过ttl后锁已过期, 续期没有生效")
+	}
+	if _, ok, err := rc.AcquireLockWithRenewal("lock", 200*time.Millisecond, 100*time.Millisecond); err != nil || ok {
+		t.Fatalf("重复获取锁 = (%t, %v), 期望 (false, nil)", ok, err)
+	}
+
+	release()
+	if m.Exists("lock") {
+		t.Fatal("release之后锁仍然存在")
+	}
+	// 不需要等待ttl过期即可重新获取
+	again, ok, err := rc.AcquireLockWithRenewal("lock", 200*time.Millisecond, 100*time.Millisecond)
+	if err != nil || !ok {
+		t.Fatalf("release之后重新获取锁 = (%t, %v), 期望获取成功", ok, err)
+	}
+	again()
+}
+
+func TestSetZRevRangeByLex(t *testing.T) {
+	rc, _ := newTestClient(t)
+	for _, member := range []string{"c", "a", "e", "b", "d"} {
+		if err := rc.SetZAdd("lex", redis.Z{Score: 0, Member: member}); err != nil {
+			t.Fatalf("SetZAdd失败: %v", err)
+		}
+	}
+
+	members, err := rc.SetZRevRangeByLex("lex", "+", "-")
+	if err != nil {
+		t.Fatalf("SetZRevRangeByLex失败: %v", err)
+	}
+	if want := []string{"e", "d", "c", "b", "a"}; !reflect.DeepEqual(members, want) {
+		t.Fatalf("SetZRevRangeByLex = %v, 期望 %v", members, want)
+	}
+}
+
+func TestListLMPop(t *testing.T) {
+	rc := newRealRedisClient(t)
+	if err := rc.ListRPush("queue:low", "job1", "job2"); err != nil {
+		t.Fatalf("ListRPush失败: %v", err)
+	}
+
+	// 高优先级列表为空，从低优先级列表弹出
+	key, values, err := rc.ListLMPop("left", 1, "queue:high", "queue:low")
+	if err != nil {
+		t.Fatalf("ListLMPop失败: %v", err)
+	}
+	if key != "queue:low" || !reflect.DeepEqual(values, []string{"job1"}) {
+		t.Fatalf("ListLMPop = (%s, %v), 期望 (queue:low, [job1])", key, values)
+	}
+}
+
+// stubHook 记录每条命令的参数；reply不为nil时以reply设置的结果代替服务端回复，
+// 用于离线测试miniredis未实现的命令，真实服务端上的行为由newRealRedisClient的测试覆盖
+type stubHook struct {
+	mu    sync.Mutex
+	args  []string
+	reply func(cmd redis.Cmder)
+}
+
+// newStubClient 返回命令经过stubHook的测试客户端
+func newStubClient(t *testing.T, reply func(cmd redis.Cmder)) (*redisClient, *stubHook) {
+	rc, _ := newTestClient(t)
+	hook := &stubHook{reply: reply}
+	rc.client().AddHook(hook)
+	return rc, hook
+}
+
+// last 返回最后一条命令的参数，格式与fmt.Sprint(cmd.Args())相同
+func (h *stubHook) last() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.args) == 0 {
+		return ""
+	}
+	return h.args[len(h.args)-1]
+}
+
+func (h *stubHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *stubHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.mu.Lock()
+		h.args = append(h.args, fmt.Sprint(cmd.Args()))
+		h.mu.Unlock()
+		if h.reply == nil {
+			return next(ctx, cmd)
+		}
+		h.reply(cmd)
+		return cmd.Err()
+	}
+}
+
+func (h *stubHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestListLMPopOffline(t *testing.T) {
+	var empty bool
+	rc, hook := newStubClient(t, func(cmd redis.Cmder) {
+		if empty {
+			cmd.SetErr(redis.Nil)
+			return
+		}
+		cmd.(*redis.KeyValuesCmd).SetVal("queue:low", []string{"job1", "job2"})
+	})
+
+	key, values, err := rc.ListLMPop("left", 2, "queue:high", "queue:low")
+	if want := "[lmpop 2 queue:high queue:low left count 2]"; hook.last() != want {
+		t.Fatalf("ListLMPop 发送的命令 = %s, 期望 %s", hook.last(), want)
+	}
+	if err != nil || key != "queue:low" || !reflect.DeepEqual(values, []string{"job1", "job2"}) {
+		t.Fatalf("ListLMPop = (%s, %v, %v), 期望 (queue:low, [job1 job2], nil)", key, values, err)
+	}
+
+	// 所有列表都为空时返回错误
+	empty = true
+	if _, _, err := rc.ListLMPop("right", 1, "queue:high"); err == nil || !strings.Contains(err.Error(), "均为空") {
+		t.Fatalf("列表均为空时ListLMPop错误 = %v, 期望列表均为空的错误", err)
+	}
+}
+
+func TestSetZMPop(t *testing.T) {
+	rc := newRealRedisClient(t)
+	if err := rc.SetZAdd("z2",
+		redis.Z{Score: 3, Member: "c"},
+		redis.Z{Score: 1, Member: "a"},
+		redis.Z{Score: 2, Member: "b"},
+	); err != nil {
+		t.Fatalf("SetZAdd失败: %v", err)
+	}
+
+	// z1不存在，从z2按分数升序弹出2个
+	key, members, err := rc.SetZMPop(true, 2, "z1", "z2")
+	if err != nil {
+		t.Fatalf("SetZMPop失败: %v", err)
+	}
+	want := []redis.Z{{Score: 1, Member: "a"}, {Score: 2, Member: "b"}}
+	if key != "z2" || !reflect.DeepEqual(members, want) {
+		t.Fatalf("SetZMPop = (%s, %v), 期望 (z2, %v)", key, members, want)
+	}
+}
+
+func TestSetZMPopOffline(t *testing.T) {
+	var empty bool
+	rc, hook := newStubClient(t, func(cmd redis.Cmder) {
+		if empty {
+			cmd.SetErr(redis.Nil)
+			return
+		}
+		cmd.(*redis.ZSliceWithKeyCmd).SetVal("z2", []redis.Z{{Score: 1, Member: "a"}})
+	})
+
+	key, members, err := rc.SetZMPop(true, 2, "z1", "z2")
+	if want := "[zmpop 2 z1 z2 min count 2]"; hook.last() != want {
+		t.Fatalf("SetZMPop(min) 发送的命令 = %s, 期望 %s", hook.last(), want)
+	}
+	if err != nil || key != "z2" || !reflect.DeepEqual(members, []redis.Z{{Score: 1, Member: "a"}}) {
+		t.Fatalf("SetZMPop = (%s, %v, %v), 期望 (z2, [{1 a}], nil)", key, members, err)
+	}
+	if _, _, err := rc.SetZMPop(false, 1, "z1"); err != nil {
+		t.Fatalf("SetZMPop(max)失败: %v", err)
+	}
+	if want := "[zmpop 1 z1 max count 1]"; hook.last() != want {
+		t.Fatalf("SetZMPop(max) 发送的命令 = %s, 期望 %s", hook.last(), want)
+	}
+
+	empty = true
+	if _, _, err := rc.SetZMPop(true, 1, "z1"); err == nil || !strings.Contains(err.Error(), "均为空") {
+		t.Fatalf("有序集合均为空时SetZMPop错误 = %v, 期望有序集合均为空的错误", err)
+	}
+}
+
+func TestSlidingTTL(t *testing.T) {
+	rc, m := newConfigTestClient(t, &RedisConfig{SlidingTTL: 60 * time.Second})
+	m.Set("session", "data")
+	m.SetTTL("session", 10*time.Second)
+
+	if _, err := rc.Get("session"); err != nil {
+		t.Fatalf("Get失败: %v", err)
+	}
+	if ttl := m.TTL("session"); ttl < 59*time.Second || ttl > 60*time.Second {
+		t.Fatalf("Get之后 TTL = %v, 期望约 60s", ttl)
+	}
+}
+
+func TestScanEach(t *testing.T) {
+	rc, m := newTestClient(t)
+	for i := 0; i < 500; i++ {
+		m.Set(fmt.Sprintf("item:%d", i), "v")
+	}
+	m.Set("other", "v")
+
+	seen := make(map[string]int)
+	if err := rc.ScanEach("item:*", 50, func(key string) error {
+		seen[key]++
+		return nil
+	}); err != nil {
+		t.Fatalf("ScanEach失败: %v", err)
+	}
+	if len(seen) != 500 {
+		t.Fatalf("遍历到 %d 个键, 期望 500", len(seen))
+	}
+	for key, n := range seen {
+		if n != 1 {
+			t.Fatalf("键 %s 被遍历 %d 次, 期望 1", key, n)
+		}
+	}
+
+	// fn返回错误时立即停止并返回该错误
+	errStop := errors.New("stop")
+	visited := 0
+	err := rc.ScanEach("item:*", 50, func(key string) error {
+		visited++
+		if visited == 10 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("ScanEach 错误 = %v, 期望 errStop", err)
+	}
+	if visited != 10 {
+		t.Fatalf("提前停止后遍历了 %d 个键, 期望 10", visited)
+	}
+}
+
+func TestListLRangeInt(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.RPush("nums", "1", "2", "3")
+	m.RPush("mixed", "1", "x", "3")
+
+	values, err := rc.ListLRangeInt("nums", 0, -1)
+	if err != nil {
+		t.Fatalf("ListLRangeInt失败: %v", err)
+	}
+	if want := []int64{1, 2, 3}; !reflect.DeepEqual(values, want) {
+		t.Fatalf("ListLRangeInt = %v, 期望 %v", values, want)
+	}
+
+	// 错误信息中包含格式错误元素的下标
+	_, err = rc.ListLRangeInt("mixed", 0, -1)
+	if !errors.Is(err, strconv.ErrSyntax) || !strings.Contains(err.Error(), "下标 1") {
+		t.Fatalf("ListLRangeInt(格式错误) 错误 = %v, 期望包含下标 1 且包装 strconv.ErrSyntax", err)
+	}
+}
+
+func TestHashGetAllBatch(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.HSet("user:1", "name", "alice")
+	m.HSet("user:2", "name", "bob", "age", "30")
+	m.HSet("user:3", "name", "carol")
+
+	hashes, err := rc.HashGetAllBatch("user:1", "user:2", "user:3")
+	if err != nil {
+		t.Fatalf("HashGetAllBatch失败: %v", err)
+	}
+	want := map[string]map[string]string{
+		"user:1": {"name": "alice"},
+		"user:2": {"name": "bob", "age": "30"},
+		"user:3": {"name": "carol"},
+	}
+	if !reflect.DeepEqual(hashes, want) {
+		t.Fatalf("HashGetAllBatch = %v, 期望 %v", hashes, want)
+	}
+}
+
+func TestLazyConnect(t *testing.T) {
+	// 端口1上没有Redis，延迟连接时创建客户端不会失败
+	rc, err := NewRedisClient(&RedisConfig{Addr: "127.0.0.1:1", LazyConnect: true}, context.Background())
+	if err != nil {
+		t.Fatalf("延迟连接创建客户端失败: %v", err)
+	}
+	defer rc.Close()
+
+	if err := rc.Set("key", "value", 0); err == nil {
+		t.Fatal("连接不可用时第一条命令未返回错误")
+	}
+}
+
+func TestSetZRangeByScoreWithScores(t *testing.T) {
+	rc, _ := newTestClient(t)
+	if err := rc.SetZAdd("z",
+		redis.Z{Score: 5, Member: "e"},
+		redis.Z{Score: 1, Member: "a"},
+		redis.Z{Score: 3, Member: "c"},
+		redis.Z{Score: 2, Member: "b"},
+		redis.Z{Score: 4, Member: "d"},
+	); err != nil {
+		t.Fatalf("SetZAdd失败: %v", err)
+	}
+
+	members, err := rc.SetZRangeByScoreWithScores("z", "2", "4", 0, -1)
+	if err != nil {
+		t.Fatalf("SetZRangeByScoreWithScores失败: %v", err)
+	}
+	want := []redis.Z{{Score: 2, Member: "b"}, {Score: 3, Member: "c"}, {Score: 4, Member: "d"}}
+	if !reflect.DeepEqual(members, want) {
+		t.Fatalf("SetZRangeByScoreWithScores = %v, 期望 %v", members, want)
+	}
+
+	tests := []struct {
+		name          string
+		reverse       bool
+		offset, count int64
+		want          []redis.Z
+	}{
+		{"升序offset和count", false, 1, 1, []redis.Z{{Score: 3, Member: "c"}}},
+		{"升序count为0", false, 1, 0, []redis.Z{}},
+		{"降序全部", true, 0, -1, []redis.Z{{Score: 4, Member: "d"}, {Score: 3, Member: "c"}, {Score: 2, Member: "b"}}},
+		{"降序offset和count", true, 1, 2, []redis.Z{{Score: 3, Member: "c"}, {Score: 2, Member: "b"}}},
+		{"降序offset不限数量", true, 2, -1, []redis.Z{{Score: 2, Member: "b"}}},
+		{"降序count为0", true, 1, 0, []redis.Z{}},
+	}
+	for _, tt := range tests {
+		var got []redis.Z
+		var err error
+		if tt.reverse {
+			// 与SetZRevRangeByScoreLimit一样先max后min
+			got, err = rc.SetZRevRangeByScoreWithScores("z", "4", "2", tt.offset, tt.count)
+		} else {
+			got, err = rc.SetZRangeByScoreWithScores("z", "2", "4", tt.offset, tt.count)
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 结果 = (%v, %v), 期望 (%v, nil)", tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := rc.SetZRevRangeByScoreWithScores("z", "2", "4", 0, -1); err == nil {
+		t.Fatal("降序时max小于min应返回错误")
+	}
+}
+
+func TestSetZRevRangeWithScores(t *testing.T) {
+	rc, _ := newTestClient(t)
+	for i, member := range []string{"a", "b", "c", "d", "e"} {
+		if err := rc.SetZAdd("z", redis.Z{Score: float64(i + 1), Member: member}); err != nil {
+			t.Fatalf("SetZAdd失败: %v", err)
+		}
+	}
+
+	members, err := rc.SetZRevRangeWithScores("z", 0, 2)
+	if err != nil {
+		t.Fatalf("SetZRevRangeWithScores失败: %v", err)
+	}
+	want := []redis.Z{{Score: 5, Member: "e"}, {Score: 4, Member: "d"}, {Score: 3, Member: "c"}}
+	if !reflect.DeepEqual(members, want) {
+		t.Fatalf("SetZRevRangeWithScores = %v, 期望 %v", members, want)
+	}
+}
+
+func TestHashDeleteAll(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.HSet("h", "f1", "v1", "f2", "v2")
+
+	deleted, err := rc.HashDeleteAll("h")
+	if err != nil || !deleted {
+		t.Fatalf("HashDeleteAll = (%t, %v), 期望 (true, nil)", deleted, err)
+	}
+	if m.Exists("h") {
+		t.Fatal("HashDeleteAll之后哈希仍然存在")
+	}
+	deleted, err = rc.HashDeleteAll("h")
+	if err != nil || deleted {
+		t.Fatalf("再次HashDeleteAll = (%t, %v), 期望 (false, nil)", deleted, err)
+	}
+}
+
+// testUser 用于JSON编码测试的结构体
+type testUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestSetAnyGetAny(t *testing.T) {
+	rc, m := newTestClient(t)
+
+	if err := rc.SetAny("int", 42, 0); err != nil {
+		t.Fatalf("SetAny(int)失败: %v", err)
+	}
+	var n int
+	if err := rc.GetAny("int", &n); err != nil || n != 42 {
+		t.Fatalf("GetAny(int) = (%d, %v), 期望 (42, nil)", n, err)
+	}
+
+	user := testUser{Name: "alice", Age: 30}
+	if err := rc.SetAny("user", user, 0); err != nil {
+		t.Fatalf("SetAny(struct)失败: %v", err)
+	}
+	var gotUser testUser
+	if err := rc.GetAny("user", &gotUser); err != nil || gotUser != user {
+		t.Fatalf("GetAny(struct) = (%+v, %v), 期望 (%+v, nil)", gotUser, err, user)
+	}
+
+	// 字符串原样保存，不做JSON编码
+	if err := rc.SetAny("str", "hello", 0); err != nil {
+		t.Fatalf("SetAny(string)失败: %v", err)
+	}
+	if raw, _ := m.Get("str"); raw != "hello" {
+		t.Fatalf("字符串保存为 %q, 期望原样保存 \"hello\"", raw)
+	}
+	var s string
+	if err := rc.GetAny("str", &s); err != nil || s != "hello" {
+		t.Fatalf("GetAny(string) = (%q, %v), 期望 (\"hello\", nil)", s, err)
+	}
+}
+
+func TestClientList(t *testing.T) {
+	rc := newRealRedisClient(t)
+
+	lines, err := rc.ClientList()
+	if err != nil {
+		t.Fatalf("ClientList失败: %v", err)
+	}
+	// 执行CLIENT LIST的连接就是本客户端的连接
+	found := false
+	for _, line := range lines {
+		if strings.Contains(line, "cmd=client|list") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("ClientList 中没有本客户端的连接: %v", lines)
+	}
+}
+
+func TestClientListOffline(t *testing.T) {
+	rc, hook := newStubClient(t, func(cmd redis.Cmder) {
+		cmd.(*redis.StringCmd).SetVal("id=3 addr=127.0.0.1:50000 name=api cmd=client|list\nid=4 addr=127.0.0.1:50001 name= cmd=get\n")
+	})
+
+	lines, err := rc.ClientList()
+	if want := "[client list]"; hook.last() != want {
+		t.Fatalf("ClientList 发送的命令 = %s, 期望 %s", hook.last(), want)
+	}
+	want := []string{"id=3 addr=127.0.0.1:50000 name=api cmd=client|list", "id=4 addr=127.0.0.1:50001 name= cmd=get"}
+	if err != nil || !reflect.DeepEqual(lines, want) {
+		t.Fatalf("ClientList = (%q, %v), 期望 (%q, nil)", lines, err, want)
+	}
+
+	// miniredis不支持CLIENT LIST，返回服务端错误
+	plain, _ := newTestClient(t)
+	if _, err := plain.ClientList(); err == nil || !strings.Contains(err.Error(), "获取客户端列表失败") {
+		t.Fatalf("服务端返回错误时ClientList错误 = %v, 期望包装服务端错误", err)
+	}
+}
+
+func TestSetZScanPairs(t *testing.T) {
+	rc, _ := newTestClient(t)
+	for i := 0; i < 60; i++ {
+		if err := rc.SetZAdd("z", redis.Z{Score: float64(i), Member: fmt.Sprintf("m%d", i)}); err != nil {
+			t.Fatalf("SetZAdd失败: %v", err)
+		}
+	}
+
+	scores := make(map[string]float64)
+	var cursor uint64
+	for {
+		members, next, err := rc.SetZScanPairs("z", cursor, "", 10)
+		if err != nil {
+			t.Fatalf("SetZScanPairs失败: %v", err)
+		}
+		for _, z := range members {
+			scores[z.Member.(string)] = z.Score
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	if len(scores) != 60 {
+		t.Fatalf("遍历到 %d 个元素, 期望 60", len(scores))
+	}
+	for i := 0; i < 60; i++ {
+		member := fmt.Sprintf("m%d", i)
+		if score, ok := scores[member]; !ok || score != float64(i) {
+			t.Fatalf("元素 %s 的分数 = (%v, %t), 期望 %d", member, score, ok, i)
+		}
+	}
+}
+
+func TestPipelineGet(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.Set("k1", "v1")
+	m.Set("k3", "v3")
+	m.Set("k5", "v5")
+
+	values, err := rc.PipelineGet("k1", "k2", "k3", "k4", "k5")
+	if err != nil {
+		t.Fatalf("PipelineGet失败: %v", err)
+	}
+	want := map[string]string{"k1": "v1", "k3": "v3", "k5": "v5"}
+	if !reflect.DeepEqual(values, want) {
+		t.Fatalf("PipelineGet = %v, 期望 %v", values, want)
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	rc, _ := newConfigTestClient(t, &RedisConfig{Identity: "order-service"})
+
+	name, err := rc.client().ClientGetName(context.Background()).Result()
+	if err != nil {
+		t.Fatalf("CLIENT GETNAME失败: %v", err)
+	}
+	if name != "order-service" {
+		t.Fatalf("连接名称 = %q, 期望 %q", name, "order-service")
+	}
+}
+
+func TestSetManyWithTTL(t *testing.T) {
+	rc, m := newTestClient(t)
+
+	err := rc.SetManyWithTTL([]SetEntry{
+		{Key: "a", Value: "1", TTL: 10 * time.Second},
+		{Key: "b", Value: "2", TTL: 20 * time.Second},
+		{Key: "c", Value: "3", TTL: 0},
+	})
+	if err != nil {
+		t.Fatalf("SetManyWithTTL失败: %v", err)
+	}
+	for key, want := range map[string]time.Duration{"a": 10 * time.Second, "b": 20 * time.Second, "c": 0} {
+		if ttl := m.TTL(key); ttl != want {
+			t.Fatalf("%s 的TTL = %v, 期望 %v", key, ttl, want)
+		}
+	}
+	if value, _ := m.Get("c"); value != "3" {
+		t.Fatalf("c = %q, 期望 \"3\"", value)
+	}
+}
+
+func TestSetSMembersInt(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.SetAdd("ids", "3", "1", "2")
+	m.SetAdd("mixed", "1", "x")
+
+	values, err := rc.SetSMembersInt("ids")
+	if err != nil {
+		t.Fatalf("SetSMembersInt失败: %v", err)
+	}
+	if want := []int64{1, 2, 3}; !reflect.DeepEqual(values, want) {
+		t.Fatalf("SetSMembersInt = %v, 期望 %v", values, want)
+	}
+
+	if _, err := rc.SetSMembersInt("mixed"); !errors.Is(err, strconv.ErrSyntax) || !strings.Contains(err.Error(), `"x"`) {
+		t.Fatalf("SetSMembersInt(格式错误) 错误 = %v, 期望包含 \"x\" 且包装 strconv.ErrSyntax", err)
+	}
+}
+
+func TestSubscribeContext(t *testing.T) {
+	rc, _ := newTestClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	msgs, err := rc.SubscribeContext(ctx, "news")
+	if err != nil {
+		t.Fatalf("SubscribeContext失败: %v", err)
+	}
+	if err := rc.client().Publish(context.Background(), "news", "hello").Err(); err != nil {
+		t.Fatalf("发布消息失败: %v", err)
+	}
+	select {
+	case msg := <-msgs:
+		if msg.Channel != "news" || msg.Payload != "hello" {
+			t.Fatalf("收到消息 %s:%s, 期望 news:hello", msg.Channel, msg.Payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("等待消息超时")
+	}
+
+	// ctx取消后消息通道关闭
+	cancel()
+	select {
+	case _, ok := <-msgs:
+		if ok {
+			t.Fatal("ctx取消后仍收到消息")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("ctx取消后消息通道未关闭")
+	}
+}
+
+func TestSetZDiffWithScores(t *testing.T) {
+	rc := newRealRedisClient(t)
+	if err := rc.SetZAdd("z1",
+		redis.Z{Score: 1, Member: "a"},
+		redis.Z{Score: 2, Member: "b"},
+		redis.Z{Score: 3, Member: "c"},
+	); err != nil {
+		t.Fatalf("SetZAdd失败: %v", err)
+	}
+	if err := rc.SetZAdd("z2", redis.Z{Score: 100, Member: "b"}); err != nil {
+		t.Fatalf("SetZAdd失败: %v", err)
+	}
+
+	members, err := rc.SetZDiffWithScores("z1", "z2")
+	if err != nil {
+		t.Fatalf("SetZDiffWithScores失败: %v", err)
+	}
+	// 差集元素保留第一个有序集合中的分数
+	want := []redis.Z{{Score: 1, Member: "a"}, {Score: 3, Member: "c"}}
+	if !reflect.DeepEqual(members, want) {
+		t.Fatalf("SetZDiffWithScores = %v, 期望 %v", members, want)
+	}
+}
+
+func TestSetZDiffWithScoresOffline(t *testing.T) {
+	rc, hook := newStubClient(t, func(cmd redis.Cmder) {
+		cmd.(*redis.ZSliceCmd).SetVal([]redis.Z{{Score: 1, Member: "a"}, {Score: 3, Member: "c"}})
+	})
+
+	members, err := rc.SetZDiffWithScores("z1", "z2")
+	if want := "[zdiff 2 z1 z2 withscores]"; hook.last() != want {
+		t.Fatalf("SetZDiffWithScores 发送的命令 = %s, 期望 %s", hook.last(), want)
+	}
+	want := []redis.Z{{Score: 1, Member: "a"}, {Score: 3, Member: "c"}}
+	if err != nil || !reflect.DeepEqual(members, want) {
+		t.Fatalf("SetZDiffWithScores = (%v, %v), 期望 (%v, nil)", members, err, want)
+	}
+
+	// miniredis不支持ZDIFF，返回服务端错误
+	plain, _ := newTestClient(t)
+	if _, err := plain.SetZDiffWithScores("z1", "z2"); err == nil || !strings.Contains(err.Error(), "获取有序集合差集失败") {
+		t.Fatalf("服务端返回错误时SetZDiffWithScores错误 = %v, 期望包装服务端错误", err)
+	}
+}
+
+func TestHashFieldExpire(t *testing.T) {
+	rc := newRealRedisClient(t)
+	if err := rc.HashSet("h", "temp", "1", "keep1", "2", "keep2", "3"); err != nil {
+		t.Fatalf("HashSet失败: %v", err)
+	}
+
+	codes, err := rc.HashFieldExpire("h", 50*time.Millisecond, "temp")
+	if err != nil {
+		t.Fatalf("HashFieldExpire失败: %v", err)
+	}
+	if !reflect.DeepEqual(codes, []int64{1}) {
+		t.Fatalf("HashFieldExpire = %v, 期望 [1]", codes)
+	}
+	if codes, err := rc.HashFieldExpire("h", time.Minute, "keep1", "missing"); err != nil || !reflect.DeepEqual(codes, []int64{1, -2}) {
+		t.Fatalf("HashFieldExpire(keep1, missing) = (%v, %v), 期望 ([1 -2], nil)", codes, err)
+	}
+	if codes, err := rc.HashFieldExpire("h", 0, "keep2"); err != nil || !reflect.DeepEqual(codes, []int64{2}) {
+		t.Fatalf("HashFieldExpire(ttl=0) = (%v, %v), 期望 ([2], nil)", codes, err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	fields, err := rc.HashGetAll("h")
+	if err != nil {
+		t.Fatalf("HashGetAll失败: %v", err)
+	}
+	if want := map[string]string{"keep1": "2"}; !reflect.DeepEqual(fields, want) {
+		t.Fatalf("字段过期后 HashGetAll = %v, 期望 %v", fields, want)
+	}
+}
+
+func TestHashFieldExpireOffline(t *testing.T) {
+	rc, hook := newStubClient(t, func(cmd redis.Cmder) {
+		cmd.(*redis.IntSliceCmd).SetVal([]int64{1, -2})
+	})
+
+	codes, err := rc.HashFieldExpire("h", 1500*time.Millisecond, "temp", "missing")
+	if want := "[HPEXPIRE h 1500 FIELDS 2 temp missing]"; hook.last() != want {
+		t.Fatalf("HashFieldExpire 发送的命令 = %s, 期望 %s", hook.last(), want)
+	}
+	if err != nil || !reflect.DeepEqual(codes, []int64{1, -2}) {
+		t.Fatalf("HashFieldExpire = (%v, %v), 期望 ([1 -2], nil)", codes, err)
+	}
+
+	// miniredis不支持HPEXPIRE，返回服务端错误
+	plain, _ := newTestClient(t)
+	if _, err := plain.HashFieldExpire("h", time.Second, "temp"); err == nil || !strings.Contains(err.Error(), "设置哈希字段过期时间失败") {
+		t.Fatalf("服务端返回错误时HashFieldExpire错误 = %v, 期望包装服务端错误", err)
+	}
+}
+
+func TestSetZCountRange(t *testing.T) {
+	rc, _ := newTestClient(t)
+	for i, score := range []float64{10, 20, 30, 40} {
+		if err := rc.SetZAdd("z", redis.Z{Score: score, Member: fmt.Sprintf("m%d", i)}); err != nil {
+			t.Fatalf("SetZAdd失败: %v", err)
+		}
+	}
+
+	tests := []struct {
+		min, max float64
+		want     int64
+	}{
+		{min: 15, max: 35, want: 2},
+		{min: math.Inf(-1), max: 25, want: 2},
+		{min: 25, max: math.Inf(1), want: 2},
+		{min: math.Inf(-1), max: math.Inf(1), want: 4},
+	}
+	for _, tt := range tests {
+		count, err := rc.SetZCountRange("z", tt.min, tt.max)
+		if err != nil {
+			t.Fatalf("SetZCountRange(%v, %v)失败: %v", tt.min, tt.max, err)
+		}
+		if count != tt.want {
+			t.Fatalf("SetZCountRange(%v, %v) = %d, 期望 %d", tt.min, tt.max, count, tt.want)
+		}
+	}
+}
+
+// countHook 按命令名称统计执行次数
+type countHook struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newCountHook() *countHook {
+	return &countHook{counts: make(map[string]int)}
+}
+
+func (h *countHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *countHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.mu.Lock()
+		h.counts[cmd.Name()]++
+		h.mu.Unlock()
+		return next(ctx, cmd)
+	}
+}
+
+func (h *countHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (h *countHook) count(name string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.counts[name]
+}
+
+func TestDeleteByPattern(t *testing.T) {
+	rc, m := newTestClient(t)
+	for i := 0; i < 1000; i++ {
+		m.Set(fmt.Sprintf("tmp:%d", i), "v")
+	}
+	m.Set("keep", "v")
+	hook := newCountHook()
+	rc.client().AddHook(hook)
+
+	deleted, err := rc.DeleteByPattern("tmp:*", 250)
+	if err != nil {
+		t.Fatalf("DeleteByPattern失败: %v", err)
+	}
+	if deleted != 1000 {
+		t.Fatalf("DeleteByPattern = %d, 期望 1000", deleted)
+	}
+	if n := hook.count("unlink"); n != 4 {
+		t.Fatalf("执行了 %d 次UNLINK, 期望 4", n)
+	}
+	if keys := m.Keys(); !reflect.DeepEqual(keys, []string{"keep"}) {
+		t.Fatalf("剩余键 = %v, 期望只有 keep", keys)
+	}
+}
+
+func TestSortList(t *testing.T) {
+	rc := newRealRedisClient(t)
+	if err := rc.ListRPush("nums", "3", "1", "2"); err != nil {
+		t.Fatalf("ListRPush失败: %v", err)
+	}
+	if err := rc.ListRPush("words", "banana", "cherry", "apple"); err != nil {
+		t.Fatalf("ListRPush失败: %v", err)
+	}
+
+	sorted, err := rc.SortList("nums", &redis.Sort{})
+	if err != nil {
+		t.Fatalf("SortList失败: %v", err)
+	}
+	if want := []string{"1", "2", "3"}; !reflect.DeepEqual(sorted, want) {
+		t.Fatalf("SortList = %v, 期望 %v", sorted, want)
+	}
+
+	sorted, err = rc.SortList("words", &redis.Sort{Alpha: true, Order: "DESC"})
+	if err != nil {
+		t.Fatalf("SortList(ALPHA DESC)失败: %v", err)
+	}
+	if want := []string{"cherry", "banana", "apple"}; !reflect.DeepEqual(sorted, want) {
+		t.Fatalf("SortList(ALPHA DESC) = %v, 期望 %v", sorted, want)
+	}
+}
+
+func TestSortListOffline(t *testing.T) {
+	rc, hook := newStubClient(t, func(cmd redis.Cmder) {
+		cmd.(*redis.StringSliceCmd).SetVal([]string{"order:2", "order:3"})
+	})
+
+	sorted, err := rc.SortList("ids", &redis.Sort{By: "weight_*", Offset: 1, Count: 2, Get: []string{"order_*"}, Alpha: true, Order: "DESC"})
+	if want := "[sort ids by weight_* limit 1 2 get order_* DESC alpha]"; hook.last() != want {
+		t.Fatalf("SortList 发送的命令 = %s, 期望 %s", hook.last(), want)
+	}
+	if err != nil || !reflect.DeepEqual(sorted, []string{"order:2", "order:3"}) {
+		t.Fatalf("SortList = (%v, %v), 期望 ([order:2 order:3], nil)", sorted, err)
+	}
+
+	// miniredis不支持SORT，返回服务端错误
+	plain, _ := newTestClient(t)
+	if _, err := plain.SortList("ids", &redis.Sort{}); err == nil || !strings.Contains(err.Error(), "排序失败") {
+		t.Fatalf("服务端返回错误时SortList错误 = %v, 期望包装服务端错误", err)
+	}
+}
+
+func TestTime(t *testing.T) {
+	rc, _ := newTestClient(t)
+
+	serverTime, err := rc.Time()
+	if err != nil {
+		t.Fatalf("Time失败: %v", err)
+	}
+	if diff := time.Since(serverTime); diff > 5*time.Second || diff < -5*time.Second {
+		t.Fatalf("服务器时间 %v 与本地时间相差 %v", serverTime, diff)
+	}
+}
+
+func TestDebugSleep(t *testing.T) {
+	rc := newRealRedisClient(t)
+
+	start := time.Now()
+	if err := rc.DebugSleep(100 * time.Millisecond); err != nil {
+		t.Fatalf("DebugSleep失败: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("DebugSleep(100ms) 只用了 %v", elapsed)
+	}
+
+	info, err := rc.DebugObject("missing")
+	if err == nil {
+		t.Fatalf("DebugObject(不存在的键) = %q, 期望返回错误", info)
+	}
+	if err := rc.Set("key", "value", 0); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+	if info, err := rc.DebugObject("key"); err != nil || !strings.Contains(info, "encoding:") {
+		t.Fatalf("DebugObject = (%q, %v), 期望包含encoding", info, err)
+	}
+}
+
+func TestDebugOffline(t *testing.T) {
+	rc, hook := newStubClient(t, func(cmd redis.Cmder) {
+		switch c := cmd.(type) {
+		case *redis.StringCmd:
+			c.SetVal("Value at:0x7f refcount:1 encoding:embstr serializedlength:6 lru:1 lru_seconds_idle:0")
+		case *redis.Cmd:
+			c.SetVal("OK")
+		}
+	})
+
+	if err := rc.DebugSleep(250 * time.Millisecond); err != nil {
+		t.Fatalf("DebugSleep失败: %v", err)
+	}
+	if want := "[DEBUG SLEEP 0.25]"; hook.last() != want {
+		t.Fatalf("DebugSleep 发送的命令 = %s, 期望 %s", hook.last(), want)
+	}
+	info, err := rc.DebugObject("key")
+	if want := "[debug object key]"; hook.last() != want {
+		t.Fatalf("DebugObject 发送的命令 = %s, 期望 %s", hook.last(), want)
+	}
+	if err != nil || !strings.Contains(info, "encoding:embstr") {
+		t.Fatalf("DebugObject = (%q, %v), 期望包含encoding", info, err)
+	}
+
+	// miniredis不支持DEBUG，返回服务端错误
+	plain, _ := newTestClient(t)
+	if err := plain.DebugSleep(time.Millisecond); err == nil || !strings.Contains(err.Error(), "DEBUG SLEEP执行失败") {
+		t.Fatalf("服务端返回错误时DebugSleep错误 = %v, 期望包装服务端错误", err)
+	}
+	if _, err := plain.DebugObject("key"); err == nil || !strings.Contains(err.Error(), "获取键调试信息失败") {
+		t.Fatalf("服务端返回错误时DebugObject错误 = %v, 期望包装服务端错误", err)
+	}
+}
+
+func TestSetZScoreMap(t *testing.T) {
+	rc, _ := newTestClient(t)
+	if err := rc.SetZAdd("z", redis.Z{Score: 1.5, Member: "a"}, redis.Z{Score: 2, Member: "b"}); err != nil {
+		t.Fatalf("SetZAdd失败: %v", err)
+	}
+
+	scores, err := rc.SetZScoreMap("z", "a", "missing", "b")
+	if err != nil {
+		t.Fatalf("SetZScoreMap失败: %v", err)
+	}
+	if want := map[string]float64{"a": 1.5, "b": 2}; !reflect.DeepEqual(scores, want) {
+		t.Fatalf("SetZScoreMap = %v, 期望 %v", scores, want)
+	}
+}
+
+func TestHashDeleteField(t *testing.T) {
+	rc, m := newTestClient(t)
+	for _, key := range []string{"h1", "h2", "h3"} {
+		m.HSet(key, "token", "x", "name", key)
+	}
+	m.HSet("h4", "name", "h4")
+
+	deleted, err := rc.HashDeleteField("token", "h1", "h2", "h3", "h4")
+	if err != nil {
+		t.Fatalf("HashDeleteField失败: %v", err)
+	}
+	if deleted != 3 {
+		t.Fatalf("HashDeleteField = %d, 期望 3", deleted)
+	}
+	for _, key := range []string{"h1", "h2", "h3"} {
+		if got, _ := m.HKeys(key); !reflect.DeepEqual(got, []string{"name"}) {
+			t.Fatalf("%s 剩余字段 = %v, 期望只有 name", key, got)
+		}
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	rc, _ := newConfigTestClient(t, &RedisConfig{PoolSize: 10, MinIdleConns: 5})
+
+	if err := rc.Warmup(); err != nil {
+		t.Fatalf("Warmup失败: %v", err)
+	}
+	if idle := rc.client().PoolStats().IdleConns; idle < 5 {
+		t.Fatalf("预热后空闲连接数 = %d, 期望至少 5", idle)
+	}
+}
+
+func TestSetNXGet(t *testing.T) {
+	rc, m := newTestClient(t)
+
+	acquired, current, err := rc.SetNXGet("leader", "node-1", time.Minute)
+	if err != nil || !acquired || current != "node-1" {
+		t.Fatalf("SetNXGet(新键) = (%t, %q, %v), 期望 (true, \"node-1\", nil)", acquired, current, err)
+	}
+	if ttl := m.TTL("leader"); ttl <= 0 {
+		t.Fatalf("TTL = %v, 期望大于0", ttl)
+	}
+
+	acquired, current, err = rc.SetNXGet("leader", "node-2", time.Minute)
+	if err != nil || acquired || current != "node-1" {
+		t.Fatalf("SetNXGet(已存在的键) = (%t, %q, %v), 期望 (false, \"node-1\", nil)", acquired, current, err)
+	}
+	if value, _ := m.Get("leader"); value != "node-1" {
+		t.Fatalf("已存在的键被修改为 %q", value)
+	}
+}
+
+func TestDecrementFloor(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.Set("stock", "10")
+
+	value, ok, err := rc.DecrementFloor("stock", 3, 0)
+	if err != nil || !ok || value != 7 {
+		t.Fatalf("DecrementFloor(3) = (%d, %t, %v), 期望 (7, true, nil)", value, ok, err)
+	}
+
+	// 低于下限时不修改
+	value, ok, err = rc.DecrementFloor("stock", 8, 0)
+	if err != nil || ok || value != 7 {
+		t.Fatalf("DecrementFloor(8) = (%d, %t, %v), 期望 (7, false, nil)", value, ok, err)
+	}
+	if stored, _ := m.Get("stock"); stored != "7" {
+		t.Fatalf("低于下限后存储的值 = %q, 期望 \"7\"", stored)
+	}
+}
+
+func TestUsername(t *testing.T) {
+	client := newClientFromConfig(&RedisConfig{Username: "app", Password: "secret"})
+	defer client.Close()
+	if opts := client.Options(); opts.Username != "app" || opts.Password != "secret" {
+		t.Fatalf("Options Username/Password = %q/%q, 期望 app/secret", opts.Username, opts.Password)
+	}
+
+	// 使用ACL用户名和密码通过认证
+	m := miniredis.RunT(t)
+	m.RequireUserAuth("app", "secret")
+	rc, err := NewRedisClient(&RedisConfig{Addr: m.Addr(), Username: "app", Password: "secret"}, context.Background())
+	if err != nil {
+		t.Fatalf("使用ACL用户名连接失败: %v", err)
+	}
+	defer rc.Close()
+	if _, err := NewRedisClient(&RedisConfig{Addr: m.Addr(), Username: "app", Password: "wrong"}, context.Background()); err == nil {
+		t.Fatal("密码错误时连接未返回错误")
+	}
+}
+
+// testClientConformance 检查客户端的基本读写行为，用于对比不同构造方式创建的客户端
+func testClientConformance(t *testing.T, rc *redisClient) {
+	t.Helper()
+	if err := rc.Set("str", "value", time.Minute); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+	if value, err := rc.Get("str"); err != nil || value != "value" {
+		t.Fatalf("Get = (%q, %v), 期望 (\"value\", nil)", value, err)
+	}
+	if _, err := rc.Get("missing"); err == nil {
+		t.Fatal("Get(不存在的键)未返回错误")
+	}
+	if err := rc.HashSet("hash", "f", "v"); err != nil {
+		t.Fatalf("HashSet失败: %v", err)
+	}
+	if value, err := rc.HashGet("hash", "f"); err != nil || value != "v" {
+		t.Fatalf("HashGet = (%q, %v), 期望 (\"v\", nil)", value, err)
+	}
+	if err := rc.ListRPush("list", "a", "b"); err != nil {
+		t.Fatalf("ListRPush失败: %v", err)
+	}
+	if values, err := rc.ListLRange("list", 0, -1); err != nil || !reflect.DeepEqual(values, []string{"a", "b"}) {
+		t.Fatalf("ListLRange = (%v, %v), 期望 ([a b], nil)", values, err)
+	}
+	if err := rc.SetZAdd("zset", redis.Z{Score: 1, Member: "m"}); err != nil {
+		t.Fatalf("SetZAdd失败: %v", err)
+	}
+	if card, err := rc.SetZCard("zset"); err != nil || card != 1 {
+		t.Fatalf("SetZCard = (%d, %v), 期望 (1, nil)", card, err)
+	}
+	if err := rc.Delete("str"); err != nil {
+		t.Fatalf("Delete失败: %v", err)
+	}
+	if exists, err := rc.Exists("str"); err != nil || exists {
+		t.Fatalf("Delete之后 Exists = (%t, %v), 期望 (false, nil)", exists, err)
+	}
+}
+
+func TestNewUniversalClient(t *testing.T) {
+	m := miniredis.RunT(t)
+	rc, err := NewUniversalClient(&redis.UniversalOptions{Addrs: []string{m.Addr()}}, context.Background())
+	if err != nil {
+		t.Fatalf("NewUniversalClient失败: %v", err)
+	}
+	defer rc.Close()
+	// 单个地址时使用单节点模式
+	if _, ok := rc.client().(*redis.Client); !ok {
+		t.Fatalf("单个地址时底层客户端类型 = %T, 期望 *redis.Client", rc.client())
+	}
+	testClientConformance(t, rc)
+
+	direct, _ := newTestClient(t)
+	testClientConformance(t, direct)
+}
+
+func TestSetSRemReport(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.SetAdd("tags", "go", "redis")
+
+	removed, err := rc.SetSRemReport("tags", "go", "missing")
+	if err != nil {
+		t.Fatalf("SetSRemReport失败: %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("SetSRemReport = %d, 期望 1", removed)
+	}
+	if members, _ := m.Members("tags"); !reflect.DeepEqual(members, []string{"redis"}) {
+		t.Fatalf("剩余元素 = %v, 期望 [redis]", members)
+	}
+}
+
+func TestClosedClient(t *testing.T) {
+	rc, _ := newConfigTestClient(t, &RedisConfig{MinIdleConns: 2})
+	rc.Close()
+
+	if err := rc.Set("key", "value", 0); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("关闭后Set错误 = %v, 期望 ErrClientClosed", err)
+	}
+	if err := rc.Warmup(); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("关闭后Warmup错误 = %v, 期望 ErrClientClosed", err)
+	}
+	// 重复关闭不会panic
+	rc.Close()
+}
+
+func TestSetZAddGT(t *testing.T) {
+	rc, m := newTestClient(t)
+	if err := rc.SetZAdd("board", redis.Z{Score: 50, Member: "alice"}); err != nil {
+		t.Fatalf("SetZAdd失败: %v", err)
+	}
+
+	changed, err := rc.SetZAddGT("board", redis.Z{Score: 40, Member: "alice"})
+	if err != nil || changed != 0 {
+		t.Fatalf("SetZAddGT(40) = (%d, %v), 期望 (0, nil)", changed, err)
+	}
+	if score, _ := m.ZScore("board", "alice"); score != 50 {
+		t.Fatalf("SetZAddGT(40)之后分数 = %v, 期望 50", score)
+	}
+
+	changed, err = rc.SetZAddGT("board", redis.Z{Score: 60, Member: "alice"})
+	if err != nil || changed != 1 {
+		t.Fatalf("SetZAddGT(60) = (%d, %v), 期望 (1, nil)", changed, err)
+	}
+	if score, _ := m.ZScore("board", "alice"); score != 60 {
+		t.Fatalf("SetZAddGT(60)之后分数 = %v, 期望 60", score)
+	}
+}
+
+func TestScript(t *testing.T) {
+	rc, _ := newTestClient(t)
+	hook := newCountHook()
+	rc.client().AddHook(hook)
+	script := rc.NewScript("return redis.call('INCR', KEYS[1])")
+
+	for i := 1; i <= 100; i++ {
+		result, err := script.Run([]string{"counter"})
+		if err != nil {
+			t.Fatalf("第 %d 次执行脚本失败: %v", i, err)
+		}
+		if result != int64(i) {
+			t.Fatalf("第 %d 次执行脚本结果 = %v, 期望 %d", i, result, i)
+		}
+	}
+	// 只有第一次需要发送脚本内容，其余都使用EVALSHA
+	if n := hook.count("eval"); n != 1 {
+		t.Fatalf("执行了 %d 次EVAL, 期望 1", n)
+	}
+	if n := hook.count("evalsha"); n != 100 {
+		t.Fatalf("执行了 %d 次EVALSHA, 期望 100", n)
+	}
+
+	// SCRIPT FLUSH之后自动回退为EVAL
+	if err := rc.client().ScriptFlush(context.Background()).Err(); err != nil {
+		t.Fatalf("SCRIPT FLUSH失败: %v", err)
+	}
+	result, err := script.Run([]string{"counter"})
+	if err != nil {
+		t.Fatalf("SCRIPT FLUSH之后执行脚本失败: %v", err)
+	}
+	if result != int64(101) {
+		t.Fatalf("SCRIPT FLUSH之后执行脚本结果 = %v, 期望 101", result)
+	}
+	if n := hook.count("eval"); n != 2 {
+		t.Fatalf("SCRIPT FLUSH之后共执行了 %d 次EVAL, 期望 2", n)
+	}
+}
+
+func TestHashPage(t *testing.T) {
+	rc, m := newTestClient(t)
+	for i := 0; i < 300; i++ {
+		m.HSet("big", fmt.Sprintf("f%d", i), strconv.Itoa(i))
+	}
+
+	seen := make(map[string]int)
+	var cursor uint64
+	for {
+		fields, next, err := rc.HashPage("big", cursor, 50)
+		if err != nil {
+			t.Fatalf("HashPage失败: %v", err)
+		}
+		for field := range fields {
+			seen[field]++
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != 300 {
+		t.Fatalf("遍历到 %d 个字段, 期望 300", len(seen))
+	}
+	for field, n := range seen {
+		if n != 1 {
+			t.Fatalf("字段 %s 出现 %d 次, 期望 1", field, n)
+		}
+	}
+}
+
+func TestListRPushCapped(t *testing.T) {
+	rc, m := newTestClient(t)
+	for i := 1; i <= 8; i++ {
+		if err := rc.ListRPushCapped("log", 5, strconv.Itoa(i)); err != nil {
+			t.Fatalf("ListRPushCapped失败: %v", err)
+		}
+	}
+
+	// 保留最新的5条
+	if values, _ := m.List("log"); !reflect.DeepEqual(values, []string{"4", "5", "6", "7", "8"}) {
+		t.Fatalf("列表 = %v, 期望 [4 5 6 7 8]", values)
+	}
+}
+
+func TestBitField(t *testing.T) {
+	rc := newRealRedisClient(t)
+
+	values, err := rc.BitField("counters", "INCRBY", "u8", 0, 10)
+	if err != nil {
+		t.Fatalf("BitField(INCRBY)失败: %v", err)
+	}
+	if !reflect.DeepEqual(values, []int64{10}) {
+		t.Fatalf("BitField(INCRBY) = %v, 期望 [10]", values)
+	}
+	values, err = rc.BitField("counters", "GET", "u8", 0)
+	if err != nil {
+		t.Fatalf("BitField(GET)失败: %v", err)
+	}
+	if !reflect.DeepEqual(values, []int64{10}) {
+		t.Fatalf("BitField(GET) = %v, 期望 [10]", values)
+	}
+}
+
+func TestBitFieldOffline(t *testing.T) {
+	rc, hook := newStubClient(t, func(cmd redis.Cmder) {
+		cmd.(*redis.IntSliceCmd).SetVal([]int64{10, 3})
+	})
+
+	values, err := rc.BitField("counters", "INCRBY", "u8", 0, 10, "GET", "u4", "#2")
+	if want := "[bitfield counters INCRBY u8 0 10 GET u4 #2]"; hook.last() != want {
+		t.Fatalf("BitField 发送的命令 = %s, 期望 %s", hook.last(), want)
+	}
+	if err != nil || !reflect.DeepEqual(values, []int64{10, 3}) {
+		t.Fatalf("BitField = (%v, %v), 期望 ([10 3], nil)", values, err)
+	}
+
+	// miniredis不支持BITFIELD，返回服务端错误
+	plain, _ := newTestClient(t)
+	if _, err := plain.BitField("counters", "GET", "u8", 0); err == nil || !strings.Contains(err.Error(), "BITFIELD执行失败") {
+		t.Fatalf("服务端返回错误时BitField错误 = %v, 期望包装服务端错误", err)
+	}
+}
+
+// fakeTracer 记录创建的span名称及结束时的错误
+type fakeTracer struct {
+	mu    sync.Mutex
+	spans []string
+	errs  []error
+}
+
+func (f *fakeTracer) StartSpan(ctx context.Context, name string) (context.Context, func(error)) {
+	f.mu.Lock()
+	f.spans = append(f.spans, name)
+	f.mu.Unlock()
+	return ctx, func(err error) {
+		f.mu.Lock()
+		f.errs = append(f.errs, err)
+		f.mu.Unlock()
+	}
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &fakeTracer{}
+	rc, m := newConfigTestClient(t, &RedisConfig{Tracer: tracer})
+	m.Set("key", "value")
+	tracer.spans, tracer.errs = nil, nil
+
+	if _, err := rc.Get("key"); err != nil {
+		t.Fatalf("Get失败: %v", err)
+	}
+	if !reflect.DeepEqual(tracer.spans, []string{"redis.GET"}) {
+		t.Fatalf("span = %v, 期望 [redis.GET]", tracer.spans)
+	}
+	if len(tracer.errs) != 1 || tracer.errs[0] != nil {
+		t.Fatalf("span结束时的错误 = %v, 期望 [nil]", tracer.errs)
+	}
+}
+
+func TestSetSRandMemberN(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.SetAdd("s", "a", "b")
+
+	// count为负数时允许重复，返回数量恰好为|count|
+	members, err := rc.SetSRandMemberN("s", -5)
+	if err != nil {
+		t.Fatalf("SetSRandMemberN失败: %v", err)
+	}
+	if len(members) != 5 {
+		t.Fatalf("SetSRandMemberN(-5) 返回 %d 个元素, 期望 5: %v", len(members), members)
+	}
+	for _, member := range members {
+		if member != "a" && member != "b" {
+			t.Fatalf("SetSRandMemberN 返回了不属于集合的元素 %q", member)
+		}
+	}
+
+	// count为正数时不重复，最多返回集合的全部元素
+	members, err = rc.SetSRandMemberN("s", 5)
+	if err != nil {
+		t.Fatalf("SetSRandMemberN失败: %v", err)
+	}
+	sort.Strings(members)
+	if !reflect.DeepEqual(members, []string{"a", "b"}) {
+		t.Fatalf("SetSRandMemberN(5) = %v, 期望 [a b]", members)
+	}
+}
+
+func TestHashScanMatch(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.HSet("h", "a:1", "x", "a:2", "y", "b:1", "z")
+
+	fields, err := rc.HashScanMatch("h", "a:*")
+	if err != nil {
+		t.Fatalf("HashScanMatch失败: %v", err)
+	}
+	if want := map[string]string{"a:1": "x", "a:2": "y"}; !reflect.DeepEqual(fields, want) {
+		t.Fatalf("HashScanMatch = %v, 期望 %v", fields, want)
+	}
+}
+
+func TestListLPushCapped(t *testing.T) {
+	rc, m := newTestClient(t)
+	for i := 1; i <= 6; i++ {
+		if err := rc.ListLPushCapped("recent", 3, strconv.Itoa(i)); err != nil {
+			t.Fatalf("ListLPushCapped失败: %v", err)
+		}
+	}
+
+	// 最新的3条保留在列表头部
+	if values, _ := m.List("recent"); !reflect.DeepEqual(values, []string{"6", "5", "4"}) {
+		t.Fatalf("列表 = %v, 期望 [6 5 4]", values)
+	}
+}
+
+func TestWrongType(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.Set("str", "value")
+
+	if _, err := rc.ListLRange("str", 0, -1); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("对字符串键ListLRange错误 = %v, 期望 ErrWrongType", err)
+	}
+}
+
+func TestBatchExists(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.Set("a", "1")
+	m.Set("c", "3")
+	m.Set("e", "5")
+
+	exists, err := rc.BatchExists("a", "b", "c", "d", "e")
+	if err != nil {
+		t.Fatalf("BatchExists失败: %v", err)
+	}
+	want := map[string]bool{"a": true, "b": false, "c": true, "d": false, "e": true}
+	if !reflect.DeepEqual(exists, want) {
+		t.Fatalf("BatchExists = %v, 期望 %v", exists, want)
+	}
+}
+
+// seedZSet 写入分数为1到n、元素为m1到mn的有序集合
+func seedZSet(t *testing.T, rc *redisClient, key string, n int) {
+	t.Helper()
+	for i := 1; i <= n; i++ {
+		if err := rc.SetZAdd(key, redis.Z{Score: float64(i), Member: fmt.Sprintf("m%d", i)}); err != nil {
+			t.Fatalf("SetZAdd失败: %v", err)
+		}
+	}
+}
+
+func TestSetZRangeByScoreLimit(t *testing.T) {
+	rc, _ := newTestClient(t)
+	seedZSet(t, rc, "z", 10)
+
+	tests := []struct {
+		offset, count int64
+		want          []string
+	}{
+		{offset: 2, count: 3, want: []string{"m3", "m4", "m5"}},
+		{offset: 0, count: 2, want: []string{"m1", "m2"}},
+		{offset: 7, count: -1, want: []string{"m8", "m9", "m10"}},
+	}
+	for _, tt := range tests {
+		members, err := rc.SetZRangeByScoreLimit("z", "-inf", "+inf", tt.offset, tt.count)
+		if err != nil {
+			t.Fatalf("SetZRangeByScoreLimit(%d, %d)失败: %v", tt.offset, tt.count, err)
+		}
+		if !reflect.DeepEqual(members, tt.want) {
+			t.Fatalf("SetZRangeByScoreLimit(%d, %d) = %v, 期望 %v", tt.offset, tt.count, members, tt.want)
+		}
+	}
+}
+
+func TestOnClose(t *testing.T) {
+	rc, m := newTestClient(t)
+
+	// 清理函数在关闭连接之前执行，此时仍可以执行命令
+	release, ok, err := rc.AcquireLockWithRenewal("lock", time.Second, 100*time.Millisecond)
+	if err != nil || !ok {
+		t.Fatalf("AcquireLockWithRenewal = (%t, %v), 期望获取成功", ok, err)
+	}
+	rc.OnClose(release)
+	var order []string
+	rc.OnClose(func() { order = append(order, "first") })
+	rc.OnClose(func() {
+		order = append(order, "second")
+		if err := rc.Set("closing", "1", 0); err != nil {
+			t.Errorf("清理函数中执行Set失败: %v", err)
+		}
+	})
+
+	stop, err := rc.SubscribeHandler([]string{"news"}, func(channel, payload string) {})
+	if err != nil {
+		t.Fatalf("SubscribeHandler失败: %v", err)
+	}
+	msgs, err := rc.SubscribeContext(context.Background(), "events")
+	if err != nil {
+		t.Fatalf("SubscribeContext失败: %v", err)
+	}
+
+	rc.Close()
+	if !reflect.DeepEqual(order, []string{"second", "first"}) {
+		t.Fatalf("清理函数执行顺序 = %v, 期望 [second first]", order)
+	}
+	if m.Exists("lock") {
+		t.Fatal("Close之后锁仍然存在")
+	}
+	if !m.Exists("closing") {
+		t.Fatal("清理函数中的Set没有生效")
+	}
+	// Close已停止所有订阅
+	if n := m.PubSubNumSub("news", "events"); n["news"] != 0 || n["events"] != 0 {
+		t.Fatalf("Close之后仍有订阅: %v", n)
+	}
+	select {
+	case _, ok := <-msgs:
+		if ok {
+			t.Fatal("Close之后仍收到消息")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close之后SubscribeContext的消息通道未关闭")
+	}
+	stop()
+
+	// 关闭后注册的清理函数立即执行
+	called := false
+	rc.OnClose(func() { called = true })
+	if !called {
+		t.Fatal("关闭后注册的清理函数没有立即执行")
+	}
+}
+
+func TestPersistMany(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.Set("volatile1", "v")
+	m.SetTTL("volatile1", time.Minute)
+	m.Set("volatile2", "v")
+	m.SetTTL("volatile2", time.Hour)
+	m.Set("permanent", "v")
+
+	persisted, err := rc.PersistMany("volatile1", "volatile2", "permanent")
+	if err != nil {
+		t.Fatalf("PersistMany失败: %v", err)
+	}
+	want := map[string]bool{"volatile1": true, "volatile2": true, "permanent": false}
+	if !reflect.DeepEqual(persisted, want) {
+		t.Fatalf("PersistMany = %v, 期望 %v", persisted, want)
+	}
+	if ttl := m.TTL("volatile1"); ttl != 0 {
+		t.Fatalf("PersistMany之后 TTL = %v, 期望 0", ttl)
+	}
+}
+
+func TestSetZIncrBy(t *testing.T) {
+	rc, _ := newTestClient(t)
+	if err := rc.SetZAdd("board", redis.Z{Score: 80, Member: "alice"}); err != nil {
+		t.Fatalf("SetZAdd失败: %v", err)
+	}
+
+	score, err := rc.SetZIncrBy("board", "alice", 10)
+	if err != nil {
+		t.Fatalf("SetZIncrBy失败: %v", err)
+	}
+	if score != 90 {
+		t.Fatalf("SetZIncrBy = %v, 期望 90", score)
+	}
+}
+
+func TestSetSCardMany(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.SetAdd("s1", "a")
+	m.SetAdd("s2", "a", "b")
+	m.SetAdd("s3", "a", "b", "c")
+
+	cards, err := rc.SetSCardMany("s1", "s2", "s3")
+	if err != nil {
+		t.Fatalf("SetSCardMany失败: %v", err)
+	}
+	if want := map[string]int64{"s1": 1, "s2": 2, "s3": 3}; !reflect.DeepEqual(cards, want) {
+		t.Fatalf("SetSCardMany = %v, 期望 %v", cards, want)
+	}
+}
+
+func TestSetZCardMany(t *testing.T) {
+	rc, _ := newTestClient(t)
+	seedZSet(t, rc, "z1", 1)
+	seedZSet(t, rc, "z2", 3)
+
+	cards, err := rc.SetZCardMany("z1", "z2", "missing")
+	if err != nil {
+		t.Fatalf("SetZCardMany失败: %v", err)
+	}
+	if want := map[string]int64{"z1": 1, "z2": 3, "missing": 0}; !reflect.DeepEqual(cards, want) {
+		t.Fatalf("SetZCardMany = %v, 期望 %v", cards, want)
+	}
+}
+
+func TestHashIncrByCapped(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.HSet("quota", "used", "7")
+
+	value, capped, err := rc.HashIncrByCapped("quota", "used", 2, 10)
+	if err != nil || capped || value != 9 {
+		t.Fatalf("HashIncrByCapped(2) = (%d, %t, %v), 期望 (9, false, nil)", value, capped, err)
+	}
+
+	// 超过上限时不修改
+	value, capped, err = rc.HashIncrByCapped("quota", "used", 5, 10)
+	if err != nil || !capped || value != 9 {
+		t.Fatalf("HashIncrByCapped(5) = (%d, %t, %v), 期望 (9, true, nil)", value, capped, err)
+	}
+	if stored := m.HGet("quota", "used"); stored != "9" {
+		t.Fatalf("超过上限后字段的值 = %q, 期望 \"9\"", stored)
+	}
+}
+
+func TestSetZTopN(t *testing.T) {
+	rc, _ := newTestClient(t)
+	seedZSet(t, rc, "board", 5)
+
+	top, err := rc.SetZTopN("board", 3)
+	if err != nil {
+		t.Fatalf("SetZTopN失败: %v", err)
+	}
+	want := []redis.Z{{Score: 5, Member: "m5"}, {Score: 4, Member: "m4"}, {Score: 3, Member: "m3"}}
+	if !reflect.DeepEqual(top, want) {
+		t.Fatalf("SetZTopN = %v, 期望 %v", top, want)
+	}
+}
+
+func TestSetZBottomN(t *testing.T) {
+	rc, _ := newTestClient(t)
+	seedZSet(t, rc, "board", 5)
+
+	bottom, err := rc.SetZBottomN("board", 2)
+	if err != nil {
+		t.Fatalf("SetZBottomN失败: %v", err)
+	}
+	want := []redis.Z{{Score: 1, Member: "m1"}, {Score: 2, Member: "m2"}}
+	if !reflect.DeepEqual(bottom, want) {
+		t.Fatalf("SetZBottomN = %v, 期望 %v", bottom, want)
+	}
+}
+
+func TestPipelineGetObjects(t *testing.T) {
+	rc, _ := newTestClient(t)
+	users := map[string]testUser{"user:1": {Name: "alice", Age: 30}, "user:2": {Name: "bob", Age: 25}}
+	for key, user := range users {
+		if err := rc.SetAny(key, user, 0); err != nil {
+			t.Fatalf("SetAny失败: %v", err)
+		}
+	}
+
+	objects, err := rc.PipelineGetObjects([]string{"user:1", "user:2", "missing"}, func() interface{} { return new(testUser) })
+	if err != nil {
+		t.Fatalf("PipelineGetObjects失败: %v", err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("PipelineGetObjects 返回 %d 个对象, 期望 2", len(objects))
+	}
+	for key, want := range users {
+		if got, ok := objects[key].(*testUser); !ok || *got != want {
+			t.Fatalf("%s = %#v, 期望 %+v", key, objects[key], want)
+		}
+	}
+}
+
+func TestConnectTimeout(t *testing.T) {
+	// 接受连接但从不回复的服务端，PING会一直等到超时
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	start := time.Now()
+	_, err = NewRedisClient(&RedisConfig{Addr: ln.Addr().String(), ConnectTimeout: 200 * time.Millisecond}, context.Background())
+	elapsed := time.Since(start)
+	if err == nil {
+		t.Fatal("服务端不回复时NewRedisClient未返回错误")
+	}
+	if elapsed < 200*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("NewRedisClient 在 %v 后失败, 期望约 200ms 而不是 %v", elapsed, DefaultConnectTimeout)
+	}
+}
+
+func TestSetBZMPop(t *testing.T) {
+	rc := newRealRedisClient(t)
+	seedZSet(t, rc, "z2", 3)
+
+	key, members, err := rc.SetBZMPop(time.Second, true, 2, "z1", "z2", "z3")
+	if err != nil {
+		t.Fatalf("SetBZMPop失败: %v", err)
+	}
+	want := []redis.Z{{Score: 1, Member: "m1"}, {Score: 2, Member: "m2"}}
+	if key != "z2" || !reflect.DeepEqual(members, want) {
+		t.Fatalf("SetBZMPop = (%s, %v), 期望 (z2, %v)", key, members, want)
+	}
+
+	// 均为空时超时返回ErrTimeout
+	if _, _, err := rc.SetBZMPop(100*time.Millisecond, true, 1, "z1", "z3"); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("均为空时SetBZMPop错误 = %v, 期望 ErrTimeout", err)
+	}
+}
+
+func TestSetBZMPopOffline(t *testing.T) {
+	var empty bool
+	rc, hook := newStubClient(t, func(cmd redis.Cmder) {
+		if empty {
+			cmd.SetErr(redis.Nil)
+			return
+		}
+		cmd.(*redis.ZSliceWithKeyCmd).SetVal("z2", []redis.Z{{Score: 3, Member: "m3"}})
+	})
+
+	key, members, err := rc.SetBZMPop(2*time.Second, false, 1, "z1", "z2")
+	if want := "[bzmpop 2 2 z1 z2 max count 1]"; hook.last() != want {
+		t.Fatalf("SetBZMPop 发送的命令 = %s, 期望 %s", hook.last(), want)
+	}
+	if err != nil || key != "z2" || !reflect.DeepEqual(members, []redis.Z{{Score: 3, Member: "m3"}}) {
+		t.Fatalf("SetBZMPop = (%s, %v, %v), 期望 (z2, [{3 m3}], nil)", key, members, err)
+	}
+
+	// 超时没有弹出元素时返回ErrTimeout
+	empty = true
+	if _, _, err := rc.SetBZMPop(time.Second, true, 1, "z1"); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("超时时SetBZMPop错误 = %v, 期望 ErrTimeout", err)
+	}
+}
+
+func TestHashCompareAndSet(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.HSet("doc", "version", "3", "title", "old")
+
+	applied, err := rc.HashCompareAndSet("doc", "version", 3, map[string]string{"title": "new"})
+	if err != nil || !applied {
+		t.Fatalf("HashCompareAndSet(版本匹配) = (%t, %v), 期望 (true, nil)", applied, err)
+	}
+	if title, version := m.HGet("doc", "title"), m.HGet("doc", "version"); title != "new" || version != "4" {
+		t.Fatalf("更新后 title=%q version=%q, 期望 new/4", title, version)
+	}
+
+	// 旧版本号不匹配，哈希保持不变
+	applied, err = rc.HashCompareAndSet("doc", "version", 3, map[string]string{"title": "stale"})
+	if err != nil || applied {
+		t.Fatalf("HashCompareAndSet(版本过期) = (%t, %v), 期望 (false, nil)", applied, err)
+	}
+	if title, version := m.HGet("doc", "title"), m.HGet("doc", "version"); title != "new" || version != "4" {
+		t.Fatalf("版本过期后 title=%q version=%q, 期望保持 new/4", title, version)
+	}
+}
+
+func TestSetSNewSince(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.SetAdd("prev", "a", "b")
+	m.SetAdd("curr", "a", "b", "c", "d")
+
+	count, err := rc.SetSNewSince("prev", "curr", "new")
+	if err != nil {
+		t.Fatalf("SetSNewSince失败: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("SetSNewSince = %d, 期望 2", count)
+	}
+	if members, _ := m.Members("new"); !reflect.DeepEqual(members, []string{"c", "d"}) {
+		t.Fatalf("新增元素 = %v, 期望 [c d]", members)
+	}
+}
+
+func TestOnConnectionChange(t *testing.T) {
+	changes := make(chan bool, 10)
+	_, m := newConfigTestClient(t, &RedisConfig{
+		HealthCheckInterval: 50 * time.Millisecond,
+		OnConnectionChange:  func(connected bool) { changes <- connected },
+	})
+
+	wait := func(want bool) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Fatalf("连接状态回调 = %t, 期望 %t", got, want)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("等待连接状态变为 %t 超时", want)
+		}
+	}
+	m.Close()
+	wait(false)
+	if err := m.Restart(); err != nil {
+		t.Fatalf("重启miniredis失败: %v", err)
+	}
+	wait(true)
+}
+
+func TestHashSetWithTTL(t *testing.T) {
+	rc, m := newTestClient(t)
+
+	if err := rc.HashSetWithTTL("session", map[string]string{"user": "alice", "role": "admin"}, time.Minute); err != nil {
+		t.Fatalf("HashSetWithTTL失败: %v", err)
+	}
+	if user, role := m.HGet("session", "user"), m.HGet("session", "role"); user != "alice" || role != "admin" {
+		t.Fatalf("字段 user=%q role=%q, 期望 alice/admin", user, role)
+	}
+	if ttl := m.TTL("session"); ttl <= 0 {
+		t.Fatalf("TTL = %v, 期望大于0", ttl)
+	}
+}
+
+func TestListLLenMany(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.RPush("q1", "a")
+	m.RPush("q2", "a", "b")
+	m.RPush("q3", "a", "b", "c")
+
+	lengths, err := rc.ListLLenMany("q1", "q2", "q3", "missing")
+	if err != nil {
+		t.Fatalf("ListLLenMany失败: %v", err)
+	}
+	if want := map[string]int64{"q1": 1, "q2": 2, "q3": 3, "missing": 0}; !reflect.DeepEqual(lengths, want) {
+		t.Fatalf("ListLLenMany = %v, 期望 %v", lengths, want)
+	}
+}
+
+func TestSetObjectCompress(t *testing.T) {
+	rc, m := newConfigTestClient(t, &RedisConfig{Compress: true})
+	user := testUser{Name: strings.Repeat("alice", 100), Age: 30}
+
+	if err := rc.SetObject("user:1", user, 0); err != nil {
+		t.Fatalf("SetObject失败: %v", err)
+	}
+	raw, _ := m.Get("user:1")
+	if !strings.HasPrefix(raw, string(compressMagic)) {
+		t.Fatal("开启Compress后写入的值没有压缩头部")
+	}
+	encoded, _ := json.Marshal(user)
+	if len(raw) >= len(encoded) {
+		t.Fatalf("压缩后的值为 %d 字节, 期望小于JSON的 %d 字节", len(raw), len(encoded))
+	}
+	var got testUser
+	if err := rc.GetObject("user:1", &got); err != nil || got != user {
+		t.Fatalf("GetObject = (%+v, %v), 期望 (%+v, nil)", got, err, user)
+	}
+
+	// PipelineGetObjects同样解压，并兼容未压缩的旧值
+	m.Set("user:2", `{"name":"bob","age":25}`)
+	objects, err := rc.PipelineGetObjects([]string{"user:1", "user:2"}, func() interface{} { return new(testUser) })
+	if err != nil {
+		t.Fatalf("PipelineGetObjects失败: %v", err)
+	}
+	if got, ok := objects["user:1"].(*testUser); !ok || *got != user {
+		t.Fatalf("PipelineGetObjects user:1 = %#v, 期望 %+v", objects["user:1"], user)
+	}
+	if got, ok := objects["user:2"].(*testUser); !ok || *got != (testUser{Name: "bob", Age: 25}) {
+		t.Fatalf("PipelineGetObjects user:2 = %#v, 期望 {bob 25}", objects["user:2"])
+	}
+
+	// 恰好以gzip头部开头的原始字节不会被当作压缩值
+	plain := "\x1f\x8b raw bytes"
+	m.Set("blob", plain)
+	var blob []byte
+	if err := rc.GetObject("blob", &blob); err != nil || string(blob) != plain {
+		t.Fatalf("GetObject(以0x1f 0x8b开头的原始值) = (%q, %v), 期望 (%q, nil)", blob, err, plain)
+	}
+}
+
+func TestSetZRevRangeByScoreLimit(t *testing.T) {
+	rc, _ := newTestClient(t)
+	seedZSet(t, rc, "z", 10)
+
+	tests := []struct {
+		offset, count int64
+		want          []string
+	}{
+		{offset: 2, count: 3, want: []string{"m8", "m7", "m6"}},
+		{offset: 7, count: -1, want: []string{"m3", "m2", "m1"}},
+	}
+	for _, tt := range tests {
+		members, err := rc.SetZRevRangeByScoreLimit("z", "+inf", "-inf", tt.offset, tt.count)
+		if err != nil {
+			t.Fatalf("SetZRevRangeByScoreLimit(%d, %d)失败: %v", tt.offset, tt.count, err)
+		}
+		if !reflect.DeepEqual(members, tt.want) {
+			t.Fatalf("SetZRevRangeByScoreLimit(%d, %d) = %v, 期望 %v", tt.offset, tt.count, members, tt.want)
+		}
+	}
+}
+
+func TestOnInvalidation(t *testing.T) {
+	rc, _ := newTestClient(t)
+
+	keys := make(chan string, 1)
+	stop, err := rc.OnInvalidation("cache:invalidate", func(key string) { keys <- key })
+	if err != nil {
+		t.Fatalf("OnInvalidation失败: %v", err)
+	}
+	if err := rc.client().Publish(context.Background(), "cache:invalidate", "user:1").Err(); err != nil {
+		t.Fatalf("发布消息失败: %v", err)
+	}
+	select {
+	case key := <-keys:
+		if key != "user:1" {
+			t.Fatalf("失效的键 = %q, 期望 \"user:1\"", key)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("等待失效通知超时")
+	}
+	stop()
+}
+
+// waitForGet 在2秒内反复执行Get，直到读到want
+func waitForGet(t *testing.T, rc *redisClient, key, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		value, err := rc.Get(key)
+		if err != nil {
+			t.Fatalf("Get失败: %v", err)
+		}
+		if value == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("2秒后Get(%s)仍返回 %q, 期望 %q", key, value, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestClientCache(t *testing.T) {
+	other := newRealRedisClient(t)
+	rc, err := NewRedisClient(&RedisConfig{Addr: os.Getenv("REDIS_TEST_ADDR"), ClientCache: true}, context.Background())
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	defer rc.Close()
+	client := rc.client().(*redis.Client)
+
+	if err := other.Set("cached", "v1", 0); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if value, err := rc.Get("cached"); err != nil || value != "v1" {
+			t.Fatalf("Get = (%q, %v), 期望 (\"v1\", nil)", value, err)
+		}
+	}
+	// 第一次读取后，后续读取由本地缓存返回
+	if hits := client.CSCStats().Hits; hits < 2 {
+		t.Fatalf("客户端缓存命中 %d 次, 期望至少 2 次", hits)
+	}
+
+	// 其他客户端或本客户端修改后，失效消息使之后的Get读到新值
+	if err := other.Set("cached", "v2", 0); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+	waitForGet(t, rc, "cached", "v2")
+	if err := rc.Set("cached", "v3", 0); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+	waitForGet(t, rc, "cached", "v3")
+}
+
+func TestClientCacheOptions(t *testing.T) {
+	client := newClientFromConfig(&RedisConfig{ClientCache: true, ClientCacheMaxEntries: 500})
+	defer client.Close()
+	opts := client.Options()
+	if opts.Protocol != 3 || opts.ClientSideCacheConfig == nil || opts.ClientSideCacheConfig.MaxEntries != 500 {
+		t.Fatalf("ClientCache Options Protocol=%d ClientSideCacheConfig=%+v, 期望RESP3且MaxEntries=500", opts.Protocol, opts.ClientSideCacheConfig)
+	}
+
+	plain := newClientFromConfig(&RedisConfig{})
+	defer plain.Close()
+	if cfg := plain.Options().ClientSideCacheConfig; cfg != nil {
+		t.Fatalf("未开启ClientCache时 ClientSideCacheConfig = %+v, 期望 nil", cfg)
+	}
+
+	// miniredis不支持CLIENT TRACKING，go-redis关闭客户端缓存，读取照常发往服务端
+	rc, m := newConfigTestClient(t, &RedisConfig{ClientCache: true})
+	m.Set("cached", "v1")
+	for i := 0; i < 3; i++ {
+		if value, err := rc.Get("cached"); err != nil || value != "v1" {
+			t.Fatalf("Get = (%q, %v), 期望 (\"v1\", nil)", value, err)
+		}
+	}
+	m.Set("cached", "v2")
+	if value, err := rc.Get("cached"); err != nil || value != "v2" {
+		t.Fatalf("客户端缓存关闭后 Get = (%q, %v), 期望 (\"v2\", nil)", value, err)
+	}
+	if hits := rc.client().(*redis.Client).CSCStats().Hits; hits != 0 {
+		t.Fatalf("客户端缓存关闭后命中 %d 次, 期望 0", hits)
+	}
+}
+
+func TestSetSInterCard(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.SetAdd("s1", "a", "b", "c", "d")
+	m.SetAdd("s2", "b", "c", "d", "e")
+
+	count, err := rc.SetSInterCard(0, "s1", "s2")
+	if err != nil || count != 3 {
+		t.Fatalf("SetSInterCard(0) = (%d, %v), 期望 (3, nil)", count, err)
+	}
+	count, err = rc.SetSInterCard(2, "s1", "s2")
+	if err != nil || count != 2 {
+		t.Fatalf("SetSInterCard(2) = (%d, %v), 期望 (2, nil)", count, err)
+	}
+}
+
+func TestDebugCommands(t *testing.T) {
+	rc, _ := newConfigTestClient(t, &RedisConfig{DebugCommands: true})
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(io.Discard)
+	if err := rc.Set("debug:key", "value", 0); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+	log.SetOutput(io.Discard)
+
+	if out := buf.String(); !strings.Contains(out, "[redis] set debug:key value") {
+		t.Fatalf("日志中没有记录SET命令: %q", out)
+	}
+}
+
+func TestSetZLexCount(t *testing.T) {
+	rc, m := newTestClient(t)
+	for _, member := range []string{"a", "b", "c", "d", "e"} {
+		m.ZAdd("lex", 0, member)
+	}
+
+	count, err := rc.SetZLexCount("lex", "[b", "[d")
+	if err != nil || count != 3 {
+		t.Fatalf("SetZLexCount([b, [d) = (%d, %v), 期望 (3, nil)", count, err)
+	}
+}
+
+func TestRenameWithTTL(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.Set("src", "payload")
+
+	if err := rc.RenameWithTTL("src", "dest", time.Minute); err != nil {
+		t.Fatalf("RenameWithTTL失败: %v", err)
+	}
+	if m.Exists("src") {
+		t.Fatal("重命名后源键仍然存在")
+	}
+	if got, err := m.Get("dest"); err != nil || got != "payload" {
+		t.Fatalf("dest = (%q, %v), 期望 payload", got, err)
+	}
+	if ttl := m.TTL("dest"); ttl <= 0 {
+		t.Fatalf("dest 的过期时间为 %v, 期望大于0", ttl)
+	}
+}
+
+func TestSetSMembersSet(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.SetAdd("tags", "go", "redis", "lua")
+
+	members, err := rc.SetSMembersSet("tags")
+	if err != nil {
+		t.Fatalf("SetSMembersSet失败: %v", err)
+	}
+	want := map[string]struct{}{"go": {}, "redis": {}, "lua": {}}
+	if !reflect.DeepEqual(members, want) {
+		t.Fatalf("SetSMembersSet = %v, 期望 %v", members, want)
+	}
+}
+
+func TestMaxValueBytesAllWritePaths(t *testing.T) {
+	rc, m := newConfigTestClient(t, &RedisConfig{MaxValueBytes: 1024})
+	big := strings.Repeat("x", 2048)
+	small := strings.Repeat("x", 500)
+
+	writes := map[string]func(value string) error{
+		"Set":           func(v string) error { return rc.Set("k", v, 0) },
+		"SetBytes":      func(v string) error { return rc.SetBytes("k", []byte(v), 0) },
+		"SetAny":        func(v string) error { return rc.SetAny("k", v, 0) },
+		"SetObject":     func(v string) error { return rc.SetObject("k", testUser{Name: v}, 0) },
+		"SetWithExpire": func(v string) error { return rc.SetWithExpire("k", v, time.Minute) },
+		"SetManyWithTTL": func(v string) error {
+			return rc.SetManyWithTTL([]SetEntry{{Key: "ok", Value: "v"}, {Key: "k", Value: v}})
+		},
+		"SetNXGet": func(v string) error {
+			_, _, err := rc.SetNXGet("k", v, 0)
+			return err
+		},
+		"SetIfNewer": func(v string) error {
+			_, err := rc.SetIfNewer("k", time.Now().UnixNano(), v, 0)
+			return err
+		},
+		"BulkLoader.Set": func(v string) error {
+			bl := rc.BulkLoad(10)
+			bl.Set("k", v)
+			return bl.Flush()
+		},
+		"Import": func(v string) error { return rc.Import(map[string]interface{}{"ok": "v", "k": v}) },
+	}
+	for name, write := range writes {
+		if err := write(big); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("%s(2KB) 返回 %v, 期望 ErrValueTooLarge", name, err)
+		}
+		if keys := m.Keys(); len(keys) != 0 {
+			t.Fatalf("%s(2KB) 被拒绝后不应写入任何键, 实际存在 %v", name, keys)
+		}
+		if err := write(small); err != nil {
+			t.Errorf("%s(500B) 失败: %v", name, err)
+		}
+		m.FlushAll()
+	}
+
+	// 开启Compress时按压缩后的大小检查: 重复内容压缩后远小于1KB，随机内容压缩后仍超过上限
+	compressed, m := newConfigTestClient(t, &RedisConfig{MaxValueBytes: 1024, Compress: true})
+	if err := compressed.SetObject("k", testUser{Name: big}, 0); err != nil {
+		t.Fatalf("压缩后不超过上限的SetObject失败: %v", err)
+	}
+	random := make([]byte, 2048)
+	for i := range random {
+		random[i] = byte(rand.Intn(256))
+	}
+	if err := compressed.SetObject("random", random, 0); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("压缩后仍超过上限的SetObject返回 %v, 期望 ErrValueTooLarge", err)
+	}
+	if m.Exists("random") {
+		t.Fatal("超过上限的压缩值不应写入")
+	}
+}
+
+func TestCmdKeys(t *testing.T) {
+	tests := []struct {
+		args []interface{}
+		want []string
+	}{
+		{[]interface{}{"get", "a"}, []string{"a"}},
+		{[]interface{}{"sintercard", 2, "a", "b", "limit", 1}, []string{"a", "b"}},
+		{[]interface{}{"lmpop", 2, "a", "b", "left"}, []string{"a", "b"}},
+		{[]interface{}{"zmpop", 1, "a", "min"}, []string{"a"}},
+		{[]interface{}{"bzmpop", 1, 2, "a", "b", "max"}, []string{"a", "b"}},
+		{[]interface{}{"zunion", 2, "a", "b", "withscores"}, []string{"a", "b"}},
+		{[]interface{}{"zinter", 2, "a", "b"}, []string{"a", "b"}},
+		{[]interface{}{"zdiff", 2, "a", "b"}, []string{"a", "b"}},
+		{[]interface{}{"zunionstore", "dest", 2, "a", "b"}, []string{"dest", "a", "b"}},
+		{[]interface{}{"rename", "a", "b"}, []string{"a", "b"}},
+		{[]interface{}{"sdiffstore", "dest", "a", "b"}, []string{"dest", "a", "b"}},
+		{[]interface{}{"blpop", "a", "b", 0}, []string{"a", "b"}},
+		{[]interface{}{"object", "encoding", "a"}, []string{"a"}},
+		{[]interface{}{"memory", "usage", "a"}, []string{"a"}},
+		{[]interface{}{"debug", "object", "a"}, []string{"a"}},
+		{[]interface{}{"debug", "sleep", 0}, []string{}},
+		{[]interface{}{"sintercard", 3, "a"}, []string{}},
+		{[]interface{}{"ping"}, nil},
+	}
+	for _, tt := range tests {
+		got := cmdKeys(redis.NewCmd(context.Background(), tt.args...))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("cmdKeys(%v) = %#v, 期望 %#v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestValidateKeysMultiKeyCommands(t *testing.T) {
+	rc, m := newConfigTestClient(t, &RedisConfig{ValidateKeys: true})
+	m.Set("src", "v")
+	m.SetAdd("s1", "a")
+
+	if err := rc.Set("bad key\n", "v", 0); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("Set(非法键名) 返回 %v, 期望 ErrInvalidKey", err)
+	}
+	if m.Exists("bad key\n") {
+		t.Fatal("非法键名不应写入")
+	}
+	if err := rc.Set("good:key", "v", 0); err != nil {
+		t.Fatalf("Set(合法键名) 失败: %v", err)
+	}
+	if got, _ := m.Get("good:key"); got != "v" {
+		t.Fatalf("good:key = %q, 期望 v", got)
+	}
+
+	if err := rc.client().Rename(rc.ctx, "src", "bad key").Err(); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("RENAME到非法键名返回 %v, 期望 ErrInvalidKey", err)
+	}
+	if err := rc.client().SDiffStore(rc.ctx, "dest", "s1", "bad\nkey").Err(); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("SDIFFSTORE包含非法键名返回 %v, 期望 ErrInvalidKey", err)
+	}
+	if _, err := rc.SetSInterCard(0, "s1", "bad key"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("SINTERCARD包含非法键名返回 %v, 期望 ErrInvalidKey", err)
+	}
+	if !m.Exists("src") || m.Exists("dest") {
+		t.Fatal("被拒绝的命令不应发送到服务端")
+	}
+}
+
+func TestGetRangeInt(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.Set("packed", "0000420000")
+
+	n, err := rc.GetRangeInt("packed", 4, 6)
+	if err != nil || n != 420 {
+		t.Fatalf("GetRangeInt(4, 6) = (%d, %v), 期望 (420, nil)", n, err)
+	}
+	if _, err := rc.GetRangeInt("packed", 20, 30); err == nil {
+		t.Fatal("超出范围时应返回错误")
+	}
+}
+
+func TestSweepIdle(t *testing.T) {
+	rc, m := newTestClient(t)
+	now := time.Now()
+	m.SetTime(now)
+	m.Set("cache:old1", "v")
+	m.Set("cache:old2", "v")
+	m.Set("other:old", "v")
+
+	// 一小时后写入的键空闲时间为0
+	m.SetTime(now.Add(time.Hour))
+	if err := rc.Set("cache:new", "v", 0); err != nil {
+		t.Fatalf("Set失败: %v", err)
+	}
+
+	deleted, err := rc.SweepIdle("cache:*", 30*time.Minute)
+	if err != nil || deleted != 2 {
+		t.Fatalf("SweepIdle = (%d, %v), 期望 (2, nil)", deleted, err)
+	}
+	keys := m.Keys()
+	if want := []string{"cache:new", "other:old"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("剩余的键 = %v, 期望 %v", keys, want)
+	}
+}
+
+func TestGetTyped(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.Set("int", "42")
+	m.Set("float", "3.5")
+	m.Set("bool", "true")
+	m.Set("bad", "abc")
+
+	if n, err := rc.GetInt("int"); err != nil || n != 42 {
+		t.Fatalf("GetInt = (%d, %v), 期望 (42, nil)", n, err)
+	}
+	if f, err := rc.GetFloat("float"); err != nil || f != 3.5 {
+		t.Fatalf("GetFloat = (%v, %v), 期望 (3.5, nil)", f, err)
+	}
+	if b, err := rc.GetBool("bool"); err != nil || !b {
+		t.Fatalf("GetBool = (%t, %v), 期望 (true, nil)", b, err)
+	}
+
+	// 值格式错误时返回的错误包装了*strconv.NumError，键不存在时则没有
+	var numErr *strconv.NumError
+	malformed := map[string]error{}
+	_, malformed["GetInt"] = rc.GetInt("bad")
+	_, malformed["GetFloat"] = rc.GetFloat("bad")
+	_, malformed["GetBool"] = rc.GetBool("bad")
+	for name, err := range malformed {
+		if !errors.As(err, &numErr) {
+			t.Errorf("%s 格式错误时返回 %v, 期望包装*strconv.NumError", name, err)
+		}
+	}
+	missing := map[string]error{}
+	_, missing["GetInt"] = rc.GetInt("missing")
+	_, missing["GetFloat"] = rc.GetFloat("missing")
+	_, missing["GetBool"] = rc.GetBool("missing")
+	for name, err := range missing {
+		if err == nil || errors.As(err, &numErr) || !strings.Contains(err.Error(), "键不存在") {
+			t.Errorf("%s 键不存在时返回 %v, 期望键不存在的错误", name, err)
+		}
+	}
+}
+
+func TestSetZUpdateScore(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.ZAdd("board", 1, "alice")
+
+	updated, err := rc.SetZUpdateScore("board", "alice", 5)
+	if err != nil || !updated {
+		t.Fatalf("更新已存在元素 = (%t, %v), 期望 (true, nil)", updated, err)
+	}
+	if score, _ := m.ZScore("board", "alice"); score != 5 {
+		t.Fatalf("alice 的分数为 %v, 期望 5", score)
+	}
+
+	updated, err = rc.SetZUpdateScore("board", "bob", 3)
+	if err != nil || updated {
+		t.Fatalf("更新不存在元素 = (%t, %v), 期望 (false, nil)", updated, err)
+	}
+	if members, _ := m.ZMembers("board"); !reflect.DeepEqual(members, []string{"alice"}) {
+		t.Fatalf("有序集合元素为 %v, 不存在的元素不应被添加", members)
+	}
+}
+
+func TestConnLifetimeOptions(t *testing.T) {
+	client := newClientFromConfig(&RedisConfig{ConnMaxIdleTime: 5 * time.Minute, ConnMaxLifetime: time.Hour})
+	defer client.Close()
+	if opts := client.Options(); opts.ConnMaxIdleTime != 5*time.Minute || opts.ConnMaxLifetime != time.Hour {
+		t.Fatalf("Options ConnMaxIdleTime/ConnMaxLifetime = %v/%v, 期望 5m/1h", opts.ConnMaxIdleTime, opts.ConnMaxLifetime)
+	}
+
+	// 为0时使用go-redis的默认值
+	defaults := newClientFromConfig(&RedisConfig{})
+	defer defaults.Close()
+	if opts := defaults.Options(); opts.ConnMaxIdleTime != 30*time.Minute || opts.ConnMaxLifetime != 0 {
+		t.Fatalf("默认 ConnMaxIdleTime/ConnMaxLifetime = %v/%v, 期望 30m/0", opts.ConnMaxIdleTime, opts.ConnMaxLifetime)
+	}
+}
+
+func TestSetZRangeByScoreMap(t *testing.T) {
+	rc, _ := newTestClient(t)
+	seedZSet(t, rc, "ranked", 5)
+
+	scores, err := rc.SetZRangeByScoreMap("ranked", "2", "4")
+	if err != nil {
+		t.Fatalf("SetZRangeByScoreMap失败: %v", err)
+	}
+	want := map[string]float64{"m2": 2, "m3": 3, "m4": 4}
+	if !reflect.DeepEqual(scores, want) {
+		t.Fatalf("SetZRangeByScoreMap = %v, 期望 %v", scores, want)
+	}
+}
+
+func TestResetKeepsSubscriptionsAndBulkLoader(t *testing.T) {
+	rc, m := newConfigTestClient(t, &RedisConfig{})
+
+	received := make(chan string, 16)
+	stop, err := rc.SubscribeHandler([]string{"news"}, func(channel, payload string) {
+		received <- payload
+	})
+	if err != nil {
+		t.Fatalf("SubscribeHandler失败: %v", err)
+	}
+	defer stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	msgs, err := rc.SubscribeContext(ctx, "events")
+	if err != nil {
+		t.Fatalf("SubscribeContext失败: %v", err)
+	}
+	bl := rc.BulkLoad(2)
+
+	if err := rc.Reset(); err != nil {
+		t.Fatalf("Reset失败: %v", err)
+	}
+
+	// 重新订阅是异步的，持续发布直到在新的连接池上收到消息
+	waitMessage := func(channel string, got func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if err := rc.client().Publish(context.Background(), channel, "after-reset").Err(); err != nil {
+				t.Fatalf("发布消息失败: %v", err)
+			}
+			if got() {
+				return
+			}
+		}
+		t.Fatalf("Reset后没有收到频道 %s 的消息", channel)
+	}
+	waitMessage("news", func() bool {
+		select {
+		case payload := <-received:
+			return payload == "after-reset"
+		case <-time.After(20 * time.Millisecond):
+			return false
+		}
+	})
+	waitMessage("events", func() bool {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				t.Fatal("Reset后SubscribeContext的通道被关闭")
+			}
+			return msg.Payload == "after-reset"
+		case <-time.After(20 * time.Millisecond):
+			return false
+		}
+	})
+
+	// Reset之前创建的BulkLoader使用新的连接池提交
+	bl.Set("bulk:1", "a")
+	bl.Set("bulk:2", "b")
+	if err := bl.Flush(); err != nil {
+		t.Fatalf("Reset后BulkLoader提交失败: %v", err)
+	}
+	if got, _ := m.Get("bulk:2"); got != "b" {
+		t.Fatalf("bulk:2 = %q, 期望 b", got)
+	}
+
+	// 停止订阅后通道关闭，不会再重新订阅
+	cancel()
+	for range msgs {
+	}
+}
+
+func TestSetSAddCard(t *testing.T) {
+	rc, m := newTestClient(t)
+	m.SetAdd("members", "a")
+
+	count, err := rc.SetSAddCard("members", "b", "c", "d")
+	if err != nil || count != 4 {
+		t.Fatalf("SetSAddCard = (%d, %v), 期望 (4, nil)", count, err)
+	}
+}